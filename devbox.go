@@ -7,6 +7,7 @@ package devbox
 import (
 	"context"
 	"io"
+	"time"
 
 	"go.jetpack.io/devbox/internal/devconfig"
 	"go.jetpack.io/devbox/internal/impl"
@@ -20,7 +21,11 @@ type Devbox interface {
 	// environment. It validates that the Nix packages exist, and install them.
 	// Adding duplicate packages is a no-op.
 	Add(ctx context.Context, pkgs ...string) error
+	// CachePush uploads the closure of the project's packages to a binary
+	// cache.
+	CachePush(ctx context.Context, cacheURL string) error
 	Config() *devconfig.Config
+	EffectiveConfig() *devconfig.Config
 	ProjectDir() string
 	// Generate creates the directory of Nix files and the Dockerfile that define
 	// the devbox environment.
@@ -34,7 +39,16 @@ type Devbox interface {
 	ListScripts() []string
 	PrintEnv(ctx context.Context, includeHooks bool) (string, error)
 	PrintEnvVars(ctx context.Context) ([]string, error)
+	// PrintEffectiveConfig prints the project's environment after includes,
+	// plugins and the lockfile are applied.
+	PrintEffectiveConfig(ctx context.Context, asJSON bool) error
 	PrintGlobalList() error
+	// PrintProcessManagers prints the running process-compose instances of
+	// the project, or of all the projects that devbox knows about.
+	PrintProcessManagers(ctx context.Context, allProjects bool) error
+	// PrintUpdatePlan prints the changes that Update would make to the
+	// lockfile, without making them.
+	PrintUpdatePlan(ctx context.Context, asJSON bool, pkgs ...string) error
 	Pull(ctx context.Context, overwrite bool, path string) error
 	Push(ctx context.Context, url string) error
 	// Remove removes Nix packages from the config so that it no longer exists in
@@ -42,15 +56,31 @@ type Devbox interface {
 	Remove(ctx context.Context, pkgs ...string) error
 	RestartServices(ctx context.Context, services ...string) error
 	RunScript(ctx context.Context, scriptName string, scriptArgs []string) error
+	// RunScriptWithServices starts services, waits until they are ready and
+	// runs a script. The services are stopped afterwards.
+	RunScriptWithServices(ctx context.Context, serviceNames []string, timeout time.Duration, scriptName string, scriptArgs []string) error
+	RunScriptsInParallel(ctx context.Context, scriptNames []string) error
+	WatchScript(ctx context.Context, scriptName string, scriptArgs []string, globs []string) error
 	Services() (services.Services, error)
+	// ServiceLogs prints the logs of the project's services.
+	ServiceLogs(ctx context.Context, opts devopt.ServiceLogsOpts) error
 	// Shell generates the devbox environment and launches nix-shell as a child process.
 	Shell(ctx context.Context) error
 	StartProcessManager(ctx context.Context, requestedServices []string, background bool, processComposeFileOrDir string) error
 	StartServices(ctx context.Context, services ...string) error
 	StopServices(ctx context.Context, allProjects bool, services ...string) error
-	ListServices(ctx context.Context) error
+	// ListServices prints the status of the project's services.
+	ListServices(ctx context.Context, w io.Writer, asJSON bool) error
+	// WaitForServices waits until the services are ready.
+	WaitForServices(ctx context.Context, serviceNames []string, timeout time.Duration) error
+	// LockSystems adds the store paths of the versioned packages for each of
+	// the systems to devbox.lock.
+	LockSystems(ctx context.Context, systems []string) error
 
 	Update(ctx context.Context, pkgs ...string) error
+	// VerifyLockfile checks that devbox.lock is consistent with devbox.json and
+	// with itself, and returns an error if it isn't.
+	VerifyLockfile(ctx context.Context, asJSON bool) error
 }
 
 // Open opens a devbox by reading the config file in dir.
@@ -67,6 +97,18 @@ func GlobalDataPath() (string, error) {
 	return impl.GlobalDataPath()
 }
 
+// PrintDiskUsage prints the disk space used by the projects that devbox knows
+// about.
+func PrintDiskUsage(ctx context.Context, w io.Writer, asJSON bool) error {
+	return impl.PrintDiskUsage(ctx, w, asJSON)
+}
+
+// GC deletes old profile generations of the projects that devbox knows about,
+// and the state of the projects that were deleted.
+func GC(ctx context.Context, w io.Writer, opts devopt.GCOpts) error {
+	return impl.GC(ctx, w, opts)
+}
+
 func PrintEnvrcContent(w io.Writer) error {
 	return impl.PrintEnvrcContent(w)
 }
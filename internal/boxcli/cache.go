@@ -0,0 +1,74 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/impl/devopt"
+)
+
+func cacheCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the binary caches of the project",
+	}
+	command.AddCommand(cachePushCmd())
+	return command
+}
+
+type cachePushCmdFlags struct {
+	config configFlags
+}
+
+func cachePushCmd() *cobra.Command {
+	flags := cachePushCmdFlags{}
+	command := &cobra.Command{
+		Use:   "push [<cache-url>]",
+		Short: "Upload the project's packages to a binary cache",
+		Long: "Install the project's packages and upload them, with everything they " +
+			"depend on, to a binary cache, so that CI and other developers can fetch " +
+			"them instead of building them. The cache can be a file://, s3:// or " +
+			"http(s):// URL, and defaults to the first substituter in devbox.json. " +
+			"Paths that the cache already has are skipped. To sign the paths, add " +
+			"?secret-key=<path to key file> to the URL.",
+		Example: "\nPush to a local directory:\n\n" +
+			"  devbox cache push file:///mnt/nix-cache\n\n" +
+			"Push to an S3 bucket:\n\n" +
+			"  devbox cache push 's3://example-nix-cache?region=us-east-1'",
+		Args:    cobra.MaximumNArgs(1),
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cachePushCmdFunc(cmd, args, flags)
+		},
+	}
+	flags.config.register(command)
+	return command
+}
+
+func cachePushCmdFunc(cmd *cobra.Command, args []string, flags cachePushCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:    flags.config.path,
+		Writer: cmd.ErrOrStderr(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	cacheURL := ""
+	if len(args) > 0 {
+		cacheURL = args[0]
+	} else if substituters := box.EffectiveConfig().Substituters; len(substituters) > 0 {
+		cacheURL = substituters[0]
+	} else {
+		return usererr.New(
+			"No binary cache to push to. Pass its URL, or add it to the " +
+				"substituters of devbox.json.",
+		)
+	}
+	return box.CachePush(cmd.Context(), cacheURL)
+}
@@ -4,7 +4,16 @@
 package boxcli
 
 import (
+	"fmt"
+
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox"
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/devconfig"
+	"go.jetpack.io/devbox/internal/impl/devopt"
+	"go.jetpack.io/devbox/internal/plugin"
 )
 
 // to be composed into xyzCmdFlags structs
@@ -23,3 +32,90 @@ func (flags *configFlags) registerPersistent(cmd *cobra.Command) {
 		&flags.path, "config", "c", "", "path to directory containing a devbox.json config file",
 	)
 }
+
+type configShowCmdFlags struct {
+	config    configFlags
+	effective bool
+	json      bool
+}
+
+func configCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration of your project",
+		Args:  cobra.MaximumNArgs(0),
+	}
+	command.AddCommand(configSchemaCmd())
+	command.AddCommand(configShowCmd())
+	return command
+}
+
+type configSchemaCmdFlags struct {
+	plugin bool
+}
+
+func configSchemaCmd() *cobra.Command {
+	flags := configSchemaCmdFlags{}
+	command := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of devbox.json",
+		Long: "Print the JSON Schema of devbox.json, or of plugins with --plugin. " +
+			"Save it to a file and reference it with \"$schema\" in devbox.json " +
+			"to get validation and completion in your editor.",
+		Args: cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema := devconfig.Schema
+			if flags.plugin {
+				schema = plugin.Schema
+			}
+			_, err := cmd.OutOrStdout().Write(schema)
+			return errors.WithStack(err)
+		},
+	}
+	command.Flags().BoolVar(
+		&flags.plugin, "plugin", false, "print the JSON Schema of plugins instead")
+	return command
+}
+
+func configShowCmd() *cobra.Command {
+	flags := configShowCmdFlags{}
+	command := &cobra.Command{
+		Use:   "show",
+		Short: "Print the configuration of your project",
+		Long: "Print the configuration of your project. With --effective, print the " +
+			"resolved environment after includes, plugins and devbox.lock are applied: " +
+			"packages with their resolved references and versions, env variables with " +
+			"their origin, init hooks in execution order, scripts and services.",
+		Args: cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return configShowCmdFunc(cmd, flags)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().BoolVar(
+		&flags.effective, "effective", false, "print the fully resolved environment")
+	command.Flags().BoolVar(&flags.json, "json", false, "output in json format")
+	return command
+}
+
+func configShowCmdFunc(cmd *cobra.Command, flags configShowCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:    flags.config.path,
+		Writer: cmd.OutOrStdout(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if flags.effective {
+		return box.PrintEffectiveConfig(cmd.Context(), flags.json)
+	}
+	// devbox.json is always json, so --json has no effect here.
+	data, err := cuecfg.MarshalJSON(box.Config())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	return errors.WithStack(err)
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox"
+	"go.jetpack.io/devbox/internal/impl/devopt"
+)
+
+func gcCmd() *cobra.Command {
+	opts := devopt.GCOpts{}
+	command := &cobra.Command{
+		Use:   "gc",
+		Short: "Reclaim disk space used by devbox projects",
+		Long: "Reclaim disk space used by the projects that devbox knows about: the " +
+			"projects you recently used with shell, run, install or services up, the " +
+			"projects that ran services, and the global profile. It deletes the old generations of each project's nix " +
+			"profile, and the .devbox directory of projects whose devbox.json was " +
+			"deleted. With --store, it also runs `nix store gc` to delete the " +
+			"packages that are no longer used.",
+		Args:    cobra.NoArgs,
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return devbox.GC(cmd.Context(), cmd.ErrOrStderr(), opts)
+		},
+	}
+	command.Flags().BoolVar(
+		&opts.DryRun, "dry-run", false,
+		"print what would be deleted without deleting it",
+	)
+	command.Flags().BoolVar(
+		&opts.Store, "store", false,
+		"also delete the packages in the nix store that are no longer used",
+	)
+	return command
+}
+
+func duCmd() *cobra.Command {
+	asJSON := false
+	command := &cobra.Command{
+		Use:   "du",
+		Short: "Show the disk space used by devbox projects",
+		Long: "Show the disk space used by the projects that devbox knows about: the " +
+			"size of each project's .devbox directory, the size of the packages in " +
+			"its nix profile, with everything they depend on, and the number of " +
+			"profile generations. Projects share packages, so the total counts each " +
+			"package once.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return devbox.PrintDiskUsage(cmd.Context(), cmd.OutOrStdout(), asJSON)
+		},
+	}
+	command.Flags().BoolVar(&asJSON, "json", false, "print the disk usage as JSON")
+	return command
+}
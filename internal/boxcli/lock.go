@@ -0,0 +1,188 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/impl/devopt"
+	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+type lockCmdFlags struct {
+	config  configFlags
+	systems []string
+}
+
+func lockCmd() *cobra.Command {
+	flags := lockCmdFlags{}
+	command := &cobra.Command{
+		Use:   "lock",
+		Short: "Manage devbox.lock",
+		Long: "Manage devbox.lock. With --systems, lock the versioned packages for " +
+			"each of the systems, so that developers on other platforms don't need " +
+			"to add their systems to devbox.lock.",
+		Example: "\nLock packages for Linux and macOS:\n\n" +
+			"  devbox lock --systems x86_64-linux,aarch64-linux,x86_64-darwin,aarch64-darwin",
+		Args: cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(flags.systems) == 0 {
+				return cmd.Help()
+			}
+			if err := ensureNixInstalled(cmd, args); err != nil {
+				return err
+			}
+			box, err := devbox.Open(&devopt.Opts{
+				Dir:    flags.config.path,
+				Writer: cmd.ErrOrStderr(),
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if err := box.LockSystems(cmd.Context(), flags.systems); err != nil {
+				return err
+			}
+			ux.Fsuccess(cmd.ErrOrStderr(), "Locked packages for %s\n", strings.Join(flags.systems, ", "))
+			return nil
+		},
+	}
+	flags.config.register(command)
+	command.Flags().StringSliceVar(
+		&flags.systems, "systems", nil,
+		"systems to lock the packages for, e.g. x86_64-linux,aarch64-darwin",
+	)
+	command.AddCommand(lockInstallMergeDriverCmd())
+	command.AddCommand(lockMergeCmd())
+	command.AddCommand(lockVerifyCmd())
+	return command
+}
+
+type lockInstallMergeDriverCmdFlags struct {
+	config configFlags
+}
+
+func lockInstallMergeDriverCmd() *cobra.Command {
+	flags := lockInstallMergeDriverCmdFlags{}
+	command := &cobra.Command{
+		Use:   "install-merge-driver",
+		Short: "Merge devbox.lock automatically when git merges branches",
+		Long: "Configure git to merge devbox.lock with `devbox lock merge`, which " +
+			"merges the packages that each branch added, removed or updated, instead " +
+			"of producing a conflict. It adds the merge driver to the git config of " +
+			"the repository, and marks devbox.lock to use it in .gitattributes. Each " +
+			"clone of the repository needs to run this command once.",
+		Args: cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			box, err := devbox.Open(&devopt.Opts{
+				Dir:    flags.config.path,
+				Writer: cmd.ErrOrStderr(),
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			added, err := lock.InstallMergeDriver(box.ProjectDir())
+			if err != nil {
+				return err
+			}
+			ux.Fsuccess(cmd.ErrOrStderr(), "Installed the devbox.lock merge driver in the git config\n")
+			if added {
+				ux.Finfo(
+					cmd.ErrOrStderr(),
+					"Added devbox.lock to .gitattributes. Commit it so that other clones use the merge driver.\n",
+				)
+			}
+			return nil
+		},
+	}
+	flags.config.register(command)
+	return command
+}
+
+func lockMergeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "merge <base> <ours> <theirs>",
+		Short: "Merge two versions of devbox.lock",
+		Long: "Three-way merge of devbox.lock, used by git as a merge driver. Packages " +
+			"that both sides changed differently are resolved again. The result is " +
+			"written to <ours>, with conflict markers if some packages can't be merged.",
+		Hidden: true,
+		Args:   cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lockMergeCmdFunc(cmd, args[0], args[1], args[2])
+		},
+	}
+}
+
+func lockMergeCmdFunc(cmd *cobra.Command, basePath, oursPath, theirsPath string) error {
+	files := make([]*lock.File, 3)
+	for i, path := range []string{basePath, oursPath, theirsPath} {
+		var err error
+		if files[i], err = lock.ReadMergeFile(path); err != nil {
+			return err
+		}
+	}
+	base, ours, theirs := files[0], files[1], files[2]
+
+	result := lock.Merge(base, ours, theirs, ours.FetchResolvedPackage)
+	data, err := result.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(oursPath, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, pkg := range result.Resolved {
+		ux.Finfo(cmd.ErrOrStderr(), "devbox.lock: both sides changed %s, resolved it again\n", pkg)
+	}
+	if len(result.Conflicts) > 0 {
+		return usererr.New(
+			"devbox.lock: both sides changed %s differently. Pick a side for each "+
+				"conflict, then run `devbox install`",
+			strings.Join(result.Conflicts, ", "),
+		)
+	}
+	return nil
+}
+
+type lockVerifyCmdFlags struct {
+	config configFlags
+	json   bool
+}
+
+func lockVerifyCmd() *cobra.Command {
+	flags := lockVerifyCmdFlags{}
+	command := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that devbox.lock is consistent with devbox.json",
+		Long: "Check that devbox.lock has an entry for every package and remote " +
+			"include in devbox.json and no stale entries, that resolved references and " +
+			"store paths are well formed and match the locked versions, and that " +
+			"plugins haven't changed version. Exits with an error if there are " +
+			"problems, so it can be used in CI. It doesn't access the network or " +
+			"change devbox.lock.",
+		Args: cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			box, err := devbox.Open(&devopt.Opts{
+				Dir:            flags.config.path,
+				CachedIncludes: true,
+				Writer:         cmd.OutOrStdout(),
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return box.VerifyLockfile(cmd.Context(), flags.json)
+		},
+	}
+	flags.config.register(command)
+	command.Flags().BoolVar(&flags.json, "json", false, "print the problems as JSON")
+	return command
+}
@@ -52,13 +52,18 @@ func RootCmd() *cobra.Command {
 	if featureflag.Auth.Enabled() {
 		command.AddCommand(authCmd())
 	}
+	command.AddCommand(cacheCmd())
+	command.AddCommand(configCmd())
 	command.AddCommand(createCmd())
+	command.AddCommand(duCmd())
+	command.AddCommand(gcCmd())
 	command.AddCommand(generateCmd())
 	command.AddCommand(globalCmd())
 	command.AddCommand(infoCmd())
 	command.AddCommand(initCmd())
 	command.AddCommand(installCmd())
 	command.AddCommand(integrateCmd())
+	command.AddCommand(lockCmd())
 	command.AddCommand(logCmd())
 	command.AddCommand(removeCmd())
 	command.AddCommand(runCmd())
@@ -5,6 +5,8 @@ package boxcli
 
 import (
 	"fmt"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -13,12 +15,18 @@ import (
 	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/impl/devopt"
 	"go.jetpack.io/devbox/internal/redact"
+	"go.jetpack.io/devbox/internal/services"
 )
 
 type runCmdFlags struct {
 	config      configFlags
 	pure        bool
 	listScripts bool
+	parallel    bool
+	watch       []string
+	watchConfig bool
+	services    []string
+	waitTimeout time.Duration
 }
 
 func runCmd() *cobra.Command {
@@ -32,7 +40,10 @@ func runCmd() *cobra.Command {
 			"after `--` will be passed verbatim into your command (see examples).\n\n",
 		Example: "\nRun a command directly:\n\n  devbox add cowsay\n  devbox run cowsay hello\n  " +
 			"devbox run -- cowsay -d hello\n\nRun a script (defined as `\"moo\": \"cowsay moo\"`) " +
-			"in your devbox.json:\n\n  devbox run moo",
+			"in your devbox.json:\n\n  devbox run moo\n\nRun several scripts at the same time:\n\n  " +
+			"devbox run lint test --parallel\n\nRun a script again whenever a Go file changes:\n\n  " +
+			"devbox run --watch '**/*.go' test\n\nRun integration tests against services:\n\n  " +
+			"devbox run --with-services postgresql,redis test",
 		PreRunE: ensureNixInstalled,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runScriptCmd(cmd, args, flags)
@@ -44,13 +55,28 @@ func runCmd() *cobra.Command {
 		&flags.pure, "pure", false, "If this flag is specified, devbox runs the script in an isolated environment inheriting almost no variables from the current environment. A few variables, in particular HOME, USER and DISPLAY, are retained.")
 	command.Flags().BoolVarP(
 		&flags.listScripts, "list", "l", false, "List all scripts defined in devbox.json")
+	command.Flags().BoolVar(
+		&flags.parallel, "parallel", false, "Run the given scripts at the same time, prefixing their output with their name. Scripts can't receive arguments with this flag.")
+	command.Flags().StringArrayVar(
+		&flags.watch, "watch", nil, "Run the script or command again whenever a file that matches this glob changes. Globs are relative to the project's directory, and files ignored by .gitignore are never watched. Can be repeated.")
+	command.Flags().BoolVar(
+		&flags.watchConfig, "watch-config", false, "Run the script again whenever a file that matches the globs in its `watch` field in devbox.json changes")
+	command.Flags().StringSliceVar(
+		&flags.services, "with-services", nil, "Start these services in the background and wait until they are ready before running the script, and stop them afterwards")
+	command.Flags().DurationVar(
+		&flags.waitTimeout, "wait-timeout", services.DefaultWaitTimeout, "How long to wait for the services of --with-services to be ready")
+	command.MarkFlagsMutuallyExclusive("parallel", "watch")
+	command.MarkFlagsMutuallyExclusive("parallel", "watch-config")
+	command.MarkFlagsMutuallyExclusive("with-services", "parallel")
+	command.MarkFlagsMutuallyExclusive("with-services", "watch")
+	command.MarkFlagsMutuallyExclusive("with-services", "watch-config")
 
 	command.ValidArgs = listScripts(command, flags)
 
 	return command
 }
 
-func listScripts(cmd *cobra.Command, flags runCmdFlags) []string {
+func openForScripts(cmd *cobra.Command, flags runCmdFlags) devbox.Devbox {
 	box, err := devbox.Open(&devopt.Opts{
 		Dir:            flags.config.path,
 		Writer:         cmd.ErrOrStderr(),
@@ -61,22 +87,41 @@ func listScripts(cmd *cobra.Command, flags runCmdFlags) []string {
 		debug.Log("failed to open devbox: %v", err)
 		return nil
 	}
+	return box
+}
 
+func listScripts(cmd *cobra.Command, flags runCmdFlags) []string {
+	box := openForScripts(cmd, flags)
+	if box == nil {
+		return nil
+	}
 	return box.ListScripts()
 }
 
+// printScripts prints the project's scripts along with their descriptions.
+func printScripts(cmd *cobra.Command, flags runCmdFlags) error {
+	box := openForScripts(cmd, flags)
+	if box == nil || len(box.ListScripts()) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "no scripts defined in devbox.json")
+		return nil
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Available scripts:")
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	scripts := box.EffectiveConfig().Scripts()
+	for _, name := range box.ListScripts() {
+		fmt.Fprintf(w, "* %s\t%s\n", name, scripts[name].Description)
+	}
+	return w.Flush()
+}
+
 func runScriptCmd(cmd *cobra.Command, args []string, flags runCmdFlags) error {
 	if len(args) == 0 || flags.listScripts {
-		scripts := listScripts(cmd, flags)
-		if len(scripts) == 0 {
-			fmt.Fprintln(cmd.OutOrStdout(), "no scripts defined in devbox.json")
-			return nil
-		}
-		fmt.Fprintln(cmd.OutOrStdout(), "Available scripts:")
-		for _, p := range scripts {
-			fmt.Fprintf(cmd.OutOrStdout(), "* %s\n", p)
-		}
-		return nil
+		return printScripts(cmd, flags)
+	}
+
+	if flags.parallel {
+		return runScriptsInParallelCmd(cmd, args, flags)
 	}
 
 	path, script, scriptArgs, err := parseScriptArgs(args, flags)
@@ -96,12 +141,35 @@ func runScriptCmd(cmd *cobra.Command, args []string, flags runCmdFlags) error {
 		return redact.Errorf("error reading devbox.json: %w", err)
 	}
 
-	if err := box.RunScript(cmd.Context(), script, scriptArgs); err != nil {
+	if len(flags.watch) > 0 || flags.watchConfig {
+		err = box.WatchScript(cmd.Context(), script, scriptArgs, flags.watch)
+	} else if len(flags.services) > 0 {
+		err = box.RunScriptWithServices(cmd.Context(), flags.services, flags.waitTimeout, script, scriptArgs)
+	} else {
+		err = box.RunScript(cmd.Context(), script, scriptArgs)
+	}
+	if err != nil {
 		return redact.Errorf("error running command in Devbox: %w", err)
 	}
 	return nil
 }
 
+func runScriptsInParallelCmd(cmd *cobra.Command, scripts []string, flags runCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:    flags.config.path,
+		Writer: cmd.ErrOrStderr(),
+		Pure:   flags.pure,
+	})
+	if err != nil {
+		return redact.Errorf("error reading devbox.json: %w", err)
+	}
+
+	if err := box.RunScriptsInParallel(cmd.Context(), scripts); err != nil {
+		return redact.Errorf("error running scripts in Devbox: %w", err)
+	}
+	return nil
+}
+
 func parseScriptArgs(args []string, flags runCmdFlags) (string, string, []string, error) {
 	if len(args) == 0 {
 		// this should never happen because cobra should prevent it, but it's better to be defensive.
@@ -4,20 +4,32 @@
 package boxcli
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"math"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/cuecfg"
 	"go.jetpack.io/devbox/internal/searcher"
+	"go.jetpack.io/devbox/internal/semver"
 	"go.jetpack.io/devbox/internal/ux"
 )
 
+// searchPageSize is the number of packages that are shown, and the number of
+// versions of each package that are shown per page.
+const searchPageSize = 10
+
 type searchCmdFlags struct {
 	showAll bool
+	json    bool
+	systems []string
+	version string
+	page    int
 }
 
 func searchCmd() *cobra.Command {
@@ -25,48 +37,162 @@ func searchCmd() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "search <pkg>",
 		Short: "Search for nix packages",
-		Args:  cobra.ExactArgs(1),
+		Long: "Search for nix packages, and show the systems that each version is " +
+			"available on. Search for <pkg>@<version> to see which version devbox " +
+			"resolves it to.",
+		Example: "\nShow the versions of go that are available on Apple Silicon:\n\n" +
+			"  devbox search go --system aarch64-darwin\n\n" +
+			"Show the versions of python between 3.10 and 3.12:\n\n" +
+			"  devbox search python --version '>=3.10 <3.12'",
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			query := args[0]
-			name, version, isVersioned := searcher.ParseVersionedPackage(query)
-			if !isVersioned {
-				results, err := searcher.Client().Search(query)
-				if err != nil {
-					return err
-				}
-				return printSearchResults(
-					cmd.OutOrStdout(), query, results, flags.showAll)
-			}
-			packageVersion, err := searcher.Client().Resolve(name, version)
-			if err != nil {
-				// This is not ideal. Search service should return valid response we
-				// can parse
-				return usererr.WithUserMessage(err, "No results found for %q\n", query)
-			}
-			fmt.Fprintf(
-				cmd.OutOrStdout(),
-				"%s resolves to: %s@%s\n",
-				query,
-				packageVersion.Name,
-				packageVersion.Version,
-			)
-			return nil
+			return searchCmdFunc(cmd, args[0], flags)
 		},
 	}
 
 	command.Flags().BoolVar(
 		&flags.showAll, "show-all", false,
-		"show all available templates",
+		"show all the packages and versions that were found",
+	)
+	command.Flags().BoolVar(
+		&flags.json, "json", false, "print the results as JSON",
+	)
+	command.Flags().StringSliceVar(
+		&flags.systems, "system", nil,
+		"only show versions that are available on these systems, e.g. aarch64-darwin",
+	)
+	command.Flags().StringVar(
+		&flags.version, "version", "",
+		"only show versions that match this version or range, e.g. '>=1.20 <1.22' or '^3.10'",
+	)
+	command.Flags().IntVar(
+		&flags.page, "page", 1,
+		fmt.Sprintf("the page of versions to show for each package, with %d versions per page",
+			searchPageSize),
 	)
+	command.AddCommand(searchIndexCmd())
 
 	return command
 }
 
+func searchCmdFunc(cmd *cobra.Command, query string, flags *searchCmdFlags) error {
+	if flags.page < 1 {
+		return usererr.New("--page must be 1 or more")
+	}
+	name, version, isVersioned := searcher.ParseVersionedPackage(query)
+	if isVersioned {
+		if flags.version != "" {
+			return usererr.New("--version can't be used when searching for %s", query)
+		}
+		return resolveSearchQuery(cmd.OutOrStdout(), query, name, version, flags)
+	}
+
+	var constraint *semver.Constraint
+	if flags.version != "" {
+		var err error
+		if constraint, err = semver.ParseConstraint(flags.version); err != nil {
+			return usererr.New("%v", err)
+		}
+	}
+	results, err := searcher.Client().Search(query)
+	if err != nil {
+		return searchError(err)
+	}
+	results = results.Filter(constraint, flags.systems)
+
+	if flags.json {
+		// JSON output is only paged when a page is requested.
+		if !flags.showAll && cmd.Flags().Changed("page") {
+			results, _ = pageSearchResults(results, flags.page)
+		}
+		return printJSON(cmd.OutOrStdout(), results)
+	}
+	return printSearchResults(cmd.OutOrStdout(), query, results, flags)
+}
+
+// resolveSearchQuery prints the version that <name>@<version> resolves to, and
+// the systems it is available on.
+func resolveSearchQuery(
+	w io.Writer,
+	query, name, version string,
+	flags *searchCmdFlags,
+) error {
+	packageVersion, err := searcher.Client().Resolve(name, version)
+	if errors.Is(err, searcher.ErrNotFound) {
+		return usererr.WithUserMessage(err, "No results found for %q\n", query)
+	}
+	if err != nil {
+		return searchError(err)
+	}
+	if flags.json {
+		return printJSON(w, packageVersion)
+	}
+
+	fmt.Fprintf(w, "%s resolves to: %s@%s\n", query, packageVersion.Name, packageVersion.Version)
+	if systems := packageVersion.SystemNames(); len(systems) > 0 {
+		fmt.Fprintf(w, "Available on: %s\n", strings.Join(systems, ", "))
+	}
+	for _, system := range flags.systems {
+		if !packageVersion.AvailableOn(system) {
+			ux.Fwarning(
+				w,
+				"%s@%s is not available on %s\n",
+				packageVersion.Name,
+				packageVersion.Version,
+				system,
+			)
+		}
+	}
+	return nil
+}
+
+// searchError explains how to search offline when the search service is
+// unavailable.
+func searchError(err error) error {
+	if errors.Is(err, searcher.ErrUnavailable) {
+		return usererr.WithUserMessage(
+			err,
+			"The package search service is unavailable. To search packages offline, "+
+				"see `devbox search index --help`.",
+		)
+	}
+	return err
+}
+
+// pageSearchResults returns the top packages, with a page of the versions of
+// each package. Packages that have no versions on the page are left out. It
+// also returns whether some packages or versions aren't included.
+func pageSearchResults(
+	results *searcher.SearchResults,
+	page int,
+) (*searcher.SearchResults, bool) {
+	start := (page - 1) * searchPageSize
+	more := false
+	paged := &searcher.SearchResults{NumResults: results.NumResults}
+	for i, pkg := range results.Packages {
+		if i >= searchPageSize {
+			more = true
+			break
+		}
+		if start < len(pkg.Versions) {
+			end := start + searchPageSize
+			if end < len(pkg.Versions) {
+				more = true
+			} else {
+				end = len(pkg.Versions)
+			}
+			pkg.Versions = pkg.Versions[start:end]
+			paged.Packages = append(paged.Packages, pkg)
+		}
+	}
+	return paged, more
+}
+
 func printSearchResults(
 	w io.Writer,
 	query string,
 	results *searcher.SearchResults,
-	showAll bool,
+	flags *searchCmdFlags,
 ) error {
 	if len(results.Packages) == 0 {
 		fmt.Fprintf(w, "No results found for %q\n", query)
@@ -80,39 +206,54 @@ func printSearchResults(
 	)
 
 	resultsAreTrimmed := false
-	pkgs := results.Packages
-	if !showAll && len(pkgs) > 10 {
-		resultsAreTrimmed = true
-		pkgs = results.Packages[:int(math.Min(10, float64(len(results.Packages))))]
-	}
-
-	for _, pkg := range pkgs {
-		nonEmptyVersions := []string{}
-		for i, v := range pkg.Versions {
-			if !showAll && i >= 10 {
-				resultsAreTrimmed = true
-				break
-			}
-			if v.Version != "" {
-				nonEmptyVersions = append(nonEmptyVersions, v.Version)
-			}
+	if !flags.showAll {
+		results, resultsAreTrimmed = pageSearchResults(results, flags.page)
+		if len(results.Packages) == 0 {
+			fmt.Fprintf(w, "No versions on page %d\n", flags.page)
+			return nil
 		}
+	}
 
-		versionString := ""
-		if len(nonEmptyVersions) > 0 {
-			versionString = fmt.Sprintf(" (%s)", strings.Join(nonEmptyVersions, ", "))
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, pkg := range results.Packages {
+		fmt.Fprintf(tw, "* %s\n", pkg.Name)
+		for _, v := range pkg.Versions {
+			if v.Version == "" {
+				continue
+			}
+			fmt.Fprintf(tw, "    %s\t%s\t%s\n", v.Version, lastUpdated(&v), strings.Join(v.SystemNames(), ", "))
 		}
-		fmt.Fprintf(w, "* %s %s\n", pkg.Name, versionString)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
 	}
 
 	if resultsAreTrimmed {
-		fmt.Println()
+		fmt.Fprintln(w)
 		ux.Fwarning(
 			w,
-			"Showing top 10 results and truncated versions. Use --show-all to "+
-				"show all.\n\n",
+			"Showing the top %d results and page %d of their versions. Use --page %d "+
+				"to show more versions, or --show-all to show all.\n\n",
+			searchPageSize, flags.page, flags.page+1,
 		)
 	}
 
 	return nil
 }
+
+// lastUpdated returns the date of the nixpkgs commit of a version.
+func lastUpdated(v *searcher.PackageVersion) string {
+	if v.LastUpdated == 0 {
+		return ""
+	}
+	return time.Unix(int64(v.LastUpdated), 0).UTC().Format("2006-01-02")
+}
+
+func printJSON(w io.Writer, v any) error {
+	data, err := cuecfg.MarshalJSON(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/searcher"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+func searchIndexCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "index",
+		Short: "Manage the offline package index",
+		Long: "Manage the offline package index, which is used to search and resolve " +
+			"packages when the search service is unreachable, or always when offline " +
+			"mode is enabled with `devbox search index offline true` or " +
+			"DEVBOX_SEARCH_OFFLINE=1. The index is stored in " +
+			"$XDG_DATA_HOME/devbox/search-index.json.gz, or in the file set by " +
+			"DEVBOX_SEARCH_INDEX.",
+		Args: cobra.MaximumNArgs(0),
+	}
+	command.AddCommand(searchIndexPullCmd())
+	command.AddCommand(searchIndexBuildCmd())
+	command.AddCommand(searchIndexInfoCmd())
+	command.AddCommand(searchIndexOfflineCmd())
+	return command
+}
+
+func searchIndexPullCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull <url | file>",
+		Short: "Download a snapshot of the package index",
+		Long:  "Download a snapshot of the package index, replacing the current offline index.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			index, err := searcher.FetchIndex(args[0])
+			if err != nil {
+				return err
+			}
+			if err := index.Save(searcher.IndexPath()); err != nil {
+				return err
+			}
+			ux.Fsuccess(
+				cmd.ErrOrStderr(),
+				"Saved %d packages to the offline package index\n",
+				len(index.Packages),
+			)
+			return nil
+		},
+	}
+}
+
+type searchIndexBuildCmdFlags struct {
+	systems []string
+}
+
+func searchIndexBuildCmd() *cobra.Command {
+	flags := searchIndexBuildCmdFlags{}
+	command := &cobra.Command{
+		Use:   "build <nixpkgs-checkout>",
+		Short: "Add the packages of a nixpkgs checkout to the offline index",
+		Long: "Add the packages of a nixpkgs git checkout to the offline package index. " +
+			"Run it on several commits of nixpkgs to index several versions of packages.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return searchIndexBuildCmdFunc(cmd, args[0], flags)
+		},
+	}
+	command.Flags().StringSliceVar(
+		&flags.systems, "system", nil,
+		"systems to index the packages for. Defaults to the current system",
+	)
+	return command
+}
+
+func searchIndexBuildCmdFunc(cmd *cobra.Command, dir string, flags searchIndexBuildCmdFlags) error {
+	systems := flags.systems
+	if len(systems) == 0 {
+		system, err := nix.System()
+		if err != nil {
+			return err
+		}
+		systems = []string{system}
+	}
+
+	index, err := searcher.LoadIndex(searcher.IndexPath())
+	if errors.Is(err, searcher.ErrNoIndex) {
+		index = &searcher.Index{}
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Indexing the packages of %s. This may take a while...\n", dir)
+	versions, err := searcher.IndexNixpkgs(dir, systems)
+	if err != nil {
+		return err
+	}
+	index.Add(versions)
+	index.Updated = time.Now().UTC()
+	if err := index.Save(searcher.IndexPath()); err != nil {
+		return err
+	}
+	ux.Fsuccess(
+		cmd.ErrOrStderr(),
+		"Indexed %d package versions. The offline index has %d packages.\n",
+		len(versions),
+		len(index.Packages),
+	)
+	return nil
+}
+
+func searchIndexInfoCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "info",
+		Short: "Show information about the offline package index",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			index, err := searcher.LoadIndex(searcher.IndexPath())
+			if err != nil {
+				return err
+			}
+			numVersions := 0
+			for _, pkg := range index.Packages {
+				numVersions += len(pkg.Versions)
+			}
+			w := cmd.OutOrStdout()
+			fmt.Fprintf(w, "Path:     %s\n", searcher.IndexPath())
+			fmt.Fprintf(w, "Updated:  %s\n", index.Updated.Format(time.RFC3339))
+			fmt.Fprintf(w, "Packages: %d\n", len(index.Packages))
+			fmt.Fprintf(w, "Versions: %d\n", numVersions)
+			fmt.Fprintf(w, "Systems:  %s\n", strings.Join(index.Systems(), ", "))
+			return nil
+		},
+	}
+}
+
+func searchIndexOfflineCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "offline [true|false]",
+		Short: "Show or set whether devbox always uses the offline package index",
+		Long: "Show or set whether devbox always uses the offline package index instead " +
+			"of the search service, for example on machines without internet access. " +
+			"The setting applies to all your projects. DEVBOX_SEARCH_OFFLINE overrides it.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings, err := searcher.LoadSettings()
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), settings.Offline)
+				return nil
+			}
+			offline, err := strconv.ParseBool(args[0])
+			if err != nil {
+				return usererr.New("invalid value %q: use true or false", args[0])
+			}
+			settings.Offline = offline
+			if err := settings.Save(); err != nil {
+				return err
+			}
+			if offline {
+				ux.Fsuccess(cmd.ErrOrStderr(), "devbox will always use the offline package index\n")
+			} else {
+				ux.Fsuccess(cmd.ErrOrStderr(), "devbox will use the search service when it's reachable\n")
+			}
+			return nil
+		},
+	}
+}
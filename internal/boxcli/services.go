@@ -4,10 +4,13 @@
 package boxcli
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"go.jetpack.io/devbox"
 	"go.jetpack.io/devbox/internal/impl/devopt"
+	"go.jetpack.io/devbox/internal/services"
 )
 
 type servicesCmdFlags struct {
@@ -17,12 +20,27 @@ type servicesCmdFlags struct {
 type serviceUpFlags struct {
 	background         bool
 	processComposeFile string
+	wait               bool
+	waitTimeout        time.Duration
 }
 
 type serviceStopFlags struct {
 	allProjects bool
 }
 
+type serviceLsFlags struct {
+	json bool
+}
+
+type servicePsFlags struct {
+	allProjects bool
+}
+
+type serviceLogsFlags struct {
+	follow bool
+	since  time.Duration
+}
+
 func (flags *serviceUpFlags) register(cmd *cobra.Command) {
 	cmd.Flags().StringVar(
 		&flags.processComposeFile,
@@ -33,6 +51,10 @@ func (flags *serviceUpFlags) register(cmd *cobra.Command) {
 	)
 	cmd.Flags().BoolVarP(
 		&flags.background, "background", "b", false, "Run service in background")
+	cmd.Flags().BoolVar(
+		&flags.wait, "wait", false, "Run services in the background and wait until they are ready")
+	cmd.Flags().DurationVar(
+		&flags.waitTimeout, "wait-timeout", services.DefaultWaitTimeout, "How long to wait for services to be ready with --wait")
 }
 
 func (flags *serviceStopFlags) register(cmd *cobra.Command) {
@@ -40,10 +62,29 @@ func (flags *serviceStopFlags) register(cmd *cobra.Command) {
 		&flags.allProjects, "all-projects", false, "Stop all running services across all your projects.\nThis flag cannot be used simultaneously with the [services] argument")
 }
 
+func (flags *serviceLsFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flags.json, "json", false, "output in json format")
+}
+
+func (flags *servicePsFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&flags.allProjects, "all-projects", false, "List the running process-compose instances of all your projects")
+}
+
+func (flags *serviceLogsFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(
+		&flags.follow, "follow", "f", false, "Print new log lines as the services write them")
+	cmd.Flags().DurationVar(
+		&flags.since, "since", 0, "Also print the logs of previous runs written to within this duration (e.g. 1h)")
+}
+
 func servicesCmd() *cobra.Command {
 	flags := servicesCmdFlags{}
 	serviceUpFlags := serviceUpFlags{}
 	serviceStopFlags := serviceStopFlags{}
+	serviceLogsFlags := serviceLogsFlags{}
+	serviceLsFlags := serviceLsFlags{}
+	servicePsFlags := servicePsFlags{}
 	servicesCommand := &cobra.Command{
 		Use:   "services",
 		Short: "Interact with devbox services",
@@ -52,9 +93,20 @@ func servicesCmd() *cobra.Command {
 	lsCommand := &cobra.Command{
 		Use:   "ls",
 		Short: "List available services",
+		Long:  "List available services. If process-compose is running, also prints the status, PID, listening ports, uptime, restarts and readiness of each service.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listServices(cmd, flags, serviceLsFlags)
+		},
+	}
+
+	psCommand := &cobra.Command{
+		Use:   "ps",
+		Short: "List the running process-compose instances of the current project",
+		Long:  "List the running process-compose instances of the current project, with their PID, port and start time.\nIf the --all-projects flag is specified, lists the running instances of all your projects.",
 		Args:  cobra.ExactArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return listServices(cmd, flags)
+			return listProcessManagers(cmd, flags, servicePsFlags)
 		},
 	}
 
@@ -91,10 +143,23 @@ func servicesCmd() *cobra.Command {
 		},
 	}
 
+	logsCommand := &cobra.Command{
+		Use:   "logs [service]...",
+		Short: "Print the logs of services. If no service is specified, prints the logs of all services",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serviceLogs(cmd, args, flags, serviceLogsFlags)
+		},
+	}
+
 	flags.config.registerPersistent(servicesCommand)
 	serviceUpFlags.register(upCommand)
 	serviceStopFlags.register(stopCommand)
+	serviceLogsFlags.register(logsCommand)
+	serviceLsFlags.register(lsCommand)
+	servicePsFlags.register(psCommand)
+	servicesCommand.AddCommand(logsCommand)
 	servicesCommand.AddCommand(lsCommand)
+	servicesCommand.AddCommand(psCommand)
 	servicesCommand.AddCommand(upCommand)
 	servicesCommand.AddCommand(restartCommand)
 	servicesCommand.AddCommand(startCommand)
@@ -102,16 +167,36 @@ func servicesCmd() *cobra.Command {
 	return servicesCommand
 }
 
-func listServices(cmd *cobra.Command, flags servicesCmdFlags) error {
+func listServices(
+	cmd *cobra.Command,
+	servicesFlags servicesCmdFlags,
+	flags serviceLsFlags,
+) error {
 	box, err := devbox.Open(&devopt.Opts{
-		Dir:    flags.config.path,
+		Dir:    servicesFlags.config.path,
 		Writer: cmd.ErrOrStderr(),
 	})
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	return box.ListServices(cmd.Context())
+	return box.ListServices(cmd.Context(), cmd.OutOrStdout(), flags.json)
+}
+
+func listProcessManagers(
+	cmd *cobra.Command,
+	servicesFlags servicesCmdFlags,
+	flags servicePsFlags,
+) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:    servicesFlags.config.path,
+		Writer: cmd.OutOrStdout(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return box.PrintProcessManagers(cmd.Context(), flags.allProjects)
 }
 
 func startServices(cmd *cobra.Command, services []string, flags servicesCmdFlags) error {
@@ -161,6 +246,27 @@ func restartServices(
 	return box.RestartServices(cmd.Context(), services...)
 }
 
+func serviceLogs(
+	cmd *cobra.Command,
+	services []string,
+	servicesFlags servicesCmdFlags,
+	flags serviceLogsFlags,
+) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:    servicesFlags.config.path,
+		Writer: cmd.OutOrStdout(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return box.ServiceLogs(cmd.Context(), devopt.ServiceLogsOpts{
+		Services: services,
+		Follow:   flags.follow,
+		Since:    flags.since,
+	})
+}
+
 func startProcessManager(
 	cmd *cobra.Command,
 	args []string,
@@ -175,5 +281,12 @@ func startProcessManager(
 		return errors.WithStack(err)
 	}
 
-	return box.StartProcessManager(cmd.Context(), args, flags.background, flags.processComposeFile)
+	// Waiting only makes sense in the background, because process-compose
+	// shows its TUI in the foreground.
+	background := flags.background || flags.wait
+	err = box.StartProcessManager(cmd.Context(), args, background, flags.processComposeFile)
+	if err != nil || !flags.wait {
+		return err
+	}
+	return box.WaitForServices(cmd.Context(), args, flags.waitTimeout)
 }
@@ -8,11 +8,14 @@ import (
 	"github.com/spf13/cobra"
 
 	"go.jetpack.io/devbox"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/internal/impl/devopt"
 )
 
 type updateCmdFlags struct {
 	config configFlags
+	dryRun bool
+	json   bool
 }
 
 func updateCmd() *cobra.Command {
@@ -24,7 +27,8 @@ func updateCmd() *cobra.Command {
 		Long: "Update one, many, or all packages in your devbox. " +
 			"If no packages are specified, all packages will be updated. " +
 			"Legacy non-versioned packages will be converted to @latest versioned " +
-			"packages resolved to their current version.",
+			"packages resolved to their current version. Use --dry-run to preview " +
+			"the changes to devbox.lock.",
 		PreRunE: ensureNixInstalled,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return updateCmdFunc(cmd, args, flags)
@@ -32,17 +36,34 @@ func updateCmd() *cobra.Command {
 	}
 
 	flags.config.register(command)
+	command.Flags().BoolVar(
+		&flags.dryRun, "dry-run", false,
+		"print the changes that would be made to devbox.lock without making them",
+	)
+	command.Flags().BoolVar(
+		&flags.json, "json", false, "print the changes of --dry-run as JSON",
+	)
 	return command
 }
 
 func updateCmdFunc(cmd *cobra.Command, args []string, flags *updateCmdFlags) error {
+	if flags.json && !flags.dryRun {
+		return usererr.New("--json can only be used with --dry-run")
+	}
+	writer := cmd.ErrOrStderr()
+	if flags.dryRun {
+		writer = cmd.OutOrStdout()
+	}
 	box, err := devbox.Open(&devopt.Opts{
 		Dir:    flags.config.path,
-		Writer: cmd.ErrOrStderr(),
+		Writer: writer,
 	})
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	if flags.dryRun {
+		return box.PrintUpdatePlan(cmd.Context(), flags.json, args...)
+	}
 	return box.Update(cmd.Context(), args...)
 }
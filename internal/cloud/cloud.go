@@ -27,6 +27,7 @@ import (
 	"go.jetpack.io/devbox/internal/cloud/openssh/sshshim"
 	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/fileutil"
 	"go.jetpack.io/devbox/internal/services"
 	"go.jetpack.io/devbox/internal/telemetry"
 	"go.jetpack.io/devbox/internal/ux/stepper"
@@ -525,29 +526,11 @@ func gitIgnorePaths(projectDir string) ([]string, error) {
 	// is platform-specific, and so we should not sync it to the cloud-shell.
 	// Platform-specific info includes nix profile links to the nix store,
 	// and in the future, versions of specific packages in the flakes.lock file.
-	result := []string{".devbox"}
-
-	fpath := filepath.Join(projectDir, ".gitignore")
-	if _, err := os.Stat(fpath); err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			return result, nil
-		}
-		return nil, errors.WithStack(err)
-	}
-
-	contents, err := os.ReadFile(fpath)
+	patterns, err := fileutil.GitIgnorePatterns(projectDir)
 	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-
-	for _, line := range strings.Split(string(contents), "\n") {
-		line = strings.TrimSpace(line)
-		if !strings.HasPrefix(line, "#") && line != "" {
-			result = append(result, line)
-		}
+		return nil, err
 	}
-
-	return result, nil
+	return append([]string{".devbox"}, patterns...), nil
 }
 
 func vmHostnameFromSSHControlPath() string {
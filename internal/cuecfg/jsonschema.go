@@ -0,0 +1,124 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package cuecfg
+
+import (
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
+	cuejson "cuelang.org/go/encoding/json"
+	"cuelang.org/go/encoding/jsonschema"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+// SchemaError is returned when a JSON document doesn't match its schema. Each
+// problem is reported on its own line, prefixed by its position in the
+// document (file:line:column).
+type SchemaError struct {
+	Filename string
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf(
+		"%s is invalid:\n  %s",
+		e.Filename,
+		strings.Join(e.Problems, "\n  "),
+	)
+}
+
+// ValidateJSONSchema validates a JSON document against a JSON Schema. Objects
+// only reject unknown keys if the schema sets "additionalProperties": false
+// in a definition that the root refers to with "$ref". filename is used to
+// report the position of errors.
+func ValidateJSONSchema(schema, data []byte, filename string) error {
+	ctx := cuecontext.New()
+
+	schemaExpr, err := cuejson.Extract("schema.json", schema)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	schemaFile, err := jsonschema.Extract(ctx.BuildExpr(schemaExpr), &jsonschema.Config{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	schemaValue := ctx.BuildFile(schemaFile)
+	if err := schemaValue.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	dataExpr, err := cuejson.Extract(filename, data)
+	if err != nil {
+		// Syntax errors are reported by the JSON decoder when the document is
+		// parsed, with better messages.
+		return nil
+	}
+	err = schemaValue.Unify(ctx.BuildExpr(dataExpr)).Validate(cue.Concrete(true))
+	if err == nil {
+		return nil
+	}
+	return newSchemaError(err, filename)
+}
+
+func newSchemaError(err error, filename string) *SchemaError {
+	type problem struct {
+		pos  token.Pos
+		path string
+		msgs []string
+	}
+	problems := []*problem{}
+	byKey := map[string]*problem{}
+	for _, e := range cueerrors.Errors(err) {
+		idx := slices.IndexFunc(e.InputPositions(), func(p token.Pos) bool {
+			return p.Filename() == filename
+		})
+		// Errors without a position in the document summarize other errors,
+		// such as "2 errors in empty disjunction".
+		if idx < 0 {
+			continue
+		}
+		pos := e.InputPositions()[idx]
+		path := strings.Join(e.Path(), ".")
+		format, args := e.Msg()
+		msg := fmt.Sprintf(format, args...)
+
+		key := pos.String() + path
+		if p, ok := byKey[key]; ok {
+			p.msgs = append(p.msgs, msg)
+			continue
+		}
+		p := &problem{pos: pos, path: path, msgs: []string{msg}}
+		byKey[key] = p
+		problems = append(problems, p)
+	}
+
+	slices.SortStableFunc(problems, func(a, b *problem) bool {
+		return a.pos.Offset() < b.pos.Offset()
+	})
+	result := &SchemaError{Filename: filename}
+	for _, p := range problems {
+		msg := p.msgs[0]
+		// A value that matches none of the alternatives of a oneOf fails
+		// once per alternative.
+		if len(p.msgs) > 1 {
+			msg = "value does not match any of the allowed types"
+		}
+		if p.path != "" {
+			msg = p.path + ": " + msg
+		}
+		result.Problems = append(
+			result.Problems,
+			fmt.Sprintf("%s:%d:%d: %s", filename, p.pos.Line(), p.pos.Column(), msg),
+		)
+	}
+	if len(result.Problems) == 0 {
+		result.Problems = []string{err.Error()}
+	}
+	return result
+}
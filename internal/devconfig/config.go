@@ -6,10 +6,12 @@ package devconfig
 import (
 	"io"
 	"net/http"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/internal/cuecfg"
@@ -20,6 +22,10 @@ const DefaultName = "devbox.json"
 
 // Config defines a devbox environment as JSON.
 type Config struct {
+	// Schema is the JSON Schema that editors use to validate devbox.json. It
+	// is kept so that saving the config doesn't remove it.
+	Schema string `json:"$schema,omitempty"`
+
 	// Packages is the slice of Nix packages that devbox makes available in
 	// its environment. Deliberately do not omitempty.
 	Packages []string `cue:"[...string]" json:"packages"`
@@ -32,12 +38,26 @@ type Config struct {
 	// Deprecated: Versioned packages don't need this
 	Nixpkgs *NixpkgsConfig `json:"nixpkgs,omitempty"`
 
-	// Reserved to allow including other config files. Proposed format is:
+	// Include allows including other config files. Supported formats are:
 	// path: for local files
 	// https:// for remote files
+	// github:owner/repo[/ref][?dir=subdir] for files hosted on github
 	// plugin: for built-in plugins
-	// This is a similar format to nix inputs
+	// This is a similar format to nix inputs. Remote includes are pinned by
+	// hash in devbox.lock.
 	Include []string `json:"include,omitempty"`
+
+	// Substituters are binary caches that nix fetches packages from, in
+	// addition to the ones in the user's nix configuration. For example
+	// https://cache.example.com, s3://bucket or file:///path/to/cache.
+	Substituters []string `json:"substituters,omitempty"`
+	// TrustedPublicKeys are the public keys that sign the packages in
+	// Substituters, like cache.example.com-1:<base64 key>.
+	TrustedPublicKeys []string `json:"trusted_public_keys,omitempty"`
+
+	// Services are long-running processes, like databases or web servers,
+	// that `devbox services` runs with process-compose.
+	Services map[string]*ServiceConfig `json:"services,omitempty"`
 }
 
 type shellConfig struct {
@@ -113,41 +133,63 @@ func (c *Config) SaveTo(path string) error {
 	return cuecfg.WriteFile(cfgPath, c)
 }
 
-func readConfig(path string) (*Config, error) {
-	cfg := &Config{}
-	return cfg, errors.WithStack(cuecfg.ParseFile(path, cfg))
-}
-
-// Load reads a devbox config file, and validates it.
+// Load reads a devbox config file, and validates it. JSON files are also
+// validated against Schema.
 func Load(path string) (*Config, error) {
-	cfg, err := readConfig(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, errors.WithStack(err)
 	}
-	return cfg, validateConfig(cfg)
+	if filepath.Ext(path) == ".json" {
+		if err := ValidateSchema(data, path); err != nil {
+			return nil, err
+		}
+	}
+	return LoadBytes(data, filepath.Ext(path))
 }
 
 func LoadConfigFromURL(url string) (*Config, error) {
-	res, err := http.Get(url)
+	data, err := FetchURL(url)
 	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-	defer res.Body.Close()
-	cfg := &Config{}
-	data, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, err
 	}
 	ext := filepath.Ext(url)
 	if !cuecfg.IsSupportedExtension(ext) {
 		ext = ".json"
 	}
-	if err = cuecfg.Unmarshal(data, ext, cfg); err != nil {
+	if ext == ".json" {
+		if err := ValidateSchema(data, url); err != nil {
+			return nil, err
+		}
+	}
+	return LoadBytes(data, ext)
+}
+
+// LoadBytes parses a devbox config encoded in the format given by ext (e.g.
+// ".json") and validates it. It doesn't validate the config against Schema
+// because included configs can also be plugins, which have their own schema.
+func LoadBytes(data []byte, ext string) (*Config, error) {
+	cfg := &Config{}
+	if err := cuecfg.Unmarshal(data, ext, cfg); err != nil {
 		return nil, errors.WithStack(err)
 	}
 	return cfg, validateConfig(cfg)
 }
 
+// FetchURL returns the raw contents of a remote devbox config.
+func FetchURL(url string) ([]byte, error) {
+	res, err := http.Get(url)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, usererr.New("failed to fetch %s: %s", url, res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	return data, errors.WithStack(err)
+}
+
 // WriteConfig saves a devbox config file.
 func WriteConfig(path string, cfg *Config) error {
 	err := validateConfig(cfg)
@@ -183,10 +225,28 @@ func validateScripts(cfg *Config) error {
 			return errors.Errorf(
 				"cannot have script name with whitespace in devbox.json: %s", k)
 		}
-		if strings.TrimSpace(scripts[k].String()) == "" {
+		// A script may only run its dependencies.
+		if strings.TrimSpace(scripts[k].String()) == "" && len(scripts[k].DependsOn) == 0 {
 			return errors.Errorf(
 				"cannot have an empty script body in devbox.json: %s", k)
 		}
+		if filepath.IsAbs(scripts[k].Cwd) || strings.HasPrefix(filepath.Clean(scripts[k].Cwd), "..") {
+			return errors.Errorf(
+				"the cwd of script %s in devbox.json must be a directory in the project: %s",
+				k, scripts[k].Cwd)
+		}
+		for _, glob := range scripts[k].Watch {
+			if !doublestar.ValidatePattern(glob) {
+				return errors.Errorf(
+					"invalid watch glob in script %s in devbox.json: %s", k, glob)
+			}
+		}
+		for _, dep := range scripts[k].DependsOn {
+			if strings.TrimSpace(dep) == "" {
+				return errors.Errorf(
+					"cannot have an empty dependency in script %s in devbox.json", k)
+			}
+		}
 	}
 	return nil
 }
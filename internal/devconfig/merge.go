@@ -0,0 +1,184 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devconfig
+
+import (
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/impl/shellcmd"
+	"go.jetpack.io/devbox/internal/searcher"
+)
+
+// IncludedConfig is a config that was included by a project's devbox.json.
+type IncludedConfig struct {
+	*Config
+
+	// Ref is the include as written in the including devbox.json
+	// (e.g. "path:../base.json" or "github:org/repo").
+	Ref string
+}
+
+// Merge computes the effective config of a project by merging the configs it
+// includes into it. Configs are merged in the following way:
+//
+//   - Precedence: the project's config has the highest precedence, followed by
+//     its includes in reverse order. That is, later includes override earlier
+//     ones.
+//   - Packages: a package whose name (without version) is listed by a config
+//     with higher precedence is dropped. Packages are ordered by precedence,
+//     starting with the project's packages, which is also the priority order
+//     used when installing them.
+//   - Env: variables are overridden by configs with higher precedence.
+//   - Init hooks: hooks are concatenated and run in include order, followed by
+//     the project's hook.
+//   - Scripts: the project's scripts override included scripts. Two includes
+//     that define a script with the same name are a conflict and return an
+//     error.
+//   - Services: services are overridden by configs with higher precedence.
+//   - Substituters and trusted public keys: the project's come first, followed
+//     by those of its includes, without duplicates.
+//
+// The nixpkgs commit and includes of the effective config are the project's.
+// Nested includes are not supported.
+//
+// Merge always returns a usable config, even if it returns an error. For
+// conflicting scripts, the first definition is used.
+func Merge(base *Config, included ...*IncludedConfig) (*Config, error) {
+	if len(included) == 0 {
+		return base, nil
+	}
+
+	merged := &Config{
+		Packages: []string{},
+		Nixpkgs:  base.Nixpkgs,
+		Include:  base.Include,
+	}
+
+	// byPrecedence is ordered from highest to lowest precedence.
+	byPrecedence := []*Config{base}
+	for i := len(included) - 1; i >= 0; i-- {
+		byPrecedence = append(byPrecedence, included[i].Config)
+	}
+
+	for _, cfg := range byPrecedence {
+		merged.Substituters = appendMissing(merged.Substituters, cfg.Substituters...)
+		merged.TrustedPublicKeys = appendMissing(merged.TrustedPublicKeys, cfg.TrustedPublicKeys...)
+	}
+
+	overridden := map[string]bool{}
+	for _, cfg := range byPrecedence {
+		names := map[string]bool{}
+		for _, pkg := range cfg.Packages {
+			name := packageName(pkg)
+			if overridden[name] || slices.Contains(merged.Packages, pkg) {
+				continue
+			}
+			names[name] = true
+			merged.Packages = append(merged.Packages, pkg)
+		}
+		for name := range names {
+			overridden[name] = true
+		}
+	}
+
+	hooks := []string{}
+	scripts := map[string]*shellcmd.Commands{}
+	scriptOrigins := map[string]string{}
+	conflicts := map[string]error{}
+	for _, inc := range included {
+		if len(inc.Include) > 0 {
+			return base, usererr.New(
+				"include %s has its own includes, but nested includes are not supported",
+				inc.Ref,
+			)
+		}
+		if len(inc.Env) > 0 && merged.Env == nil {
+			merged.Env = map[string]string{}
+		}
+		maps.Copy(merged.Env, inc.Env)
+		if len(inc.Services) > 0 && merged.Services == nil {
+			merged.Services = map[string]*ServiceConfig{}
+		}
+		maps.Copy(merged.Services, inc.Services)
+		if hook := inc.InitHook(); hook != nil {
+			hooks = append(hooks, hook.Cmds...)
+		}
+		for name, script := range inc.Scripts() {
+			if origin, ok := scriptOrigins[name]; ok {
+				if conflicts[name] == nil {
+					conflicts[name] = usererr.New(
+						"script %q is defined by both includes %s and %s. "+
+							"Define it in your devbox.json to override both.",
+						name, origin, inc.Ref,
+					)
+				}
+				continue
+			}
+			scripts[name] = script
+			scriptOrigins[name] = inc.Ref
+		}
+	}
+
+	if len(base.Env) > 0 && merged.Env == nil {
+		merged.Env = map[string]string{}
+	}
+	maps.Copy(merged.Env, base.Env)
+	if len(base.Services) > 0 && merged.Services == nil {
+		merged.Services = map[string]*ServiceConfig{}
+	}
+	maps.Copy(merged.Services, base.Services)
+	for name, script := range base.Scripts() {
+		// The project's script overrides included ones, so it resolves any
+		// conflict between them.
+		scripts[name] = script
+		delete(conflicts, name)
+	}
+
+	var err error
+	if len(conflicts) > 0 {
+		names := maps.Keys(conflicts)
+		slices.Sort(names)
+		err = conflicts[names[0]]
+	}
+
+	initHook := base.InitHook()
+	if initHook != nil {
+		hooks = append(hooks, initHook.Cmds...)
+	}
+	if len(hooks) > 0 || len(scripts) > 0 {
+		merged.Shell = &shellConfig{}
+		if len(hooks) > 0 {
+			merged.Shell.InitHook = &shellcmd.Commands{Cmds: hooks}
+			if initHook != nil {
+				merged.Shell.InitHook.MarshalAs = initHook.MarshalAs
+			}
+		}
+		if len(scripts) > 0 {
+			merged.Shell.Scripts = scripts
+		}
+	}
+
+	return merged, err
+}
+
+// packageName returns the name of a package without its version, so that
+// different versions of the same package override each other.
+func packageName(pkg string) string {
+	if name, _, versioned := searcher.ParseVersionedPackage(pkg); versioned {
+		return name
+	}
+	return pkg
+}
+
+// appendMissing appends the values that aren't in s yet.
+func appendMissing(s []string, values ...string) []string {
+	for _, value := range values {
+		if !slices.Contains(s, value) {
+			s = append(s, value)
+		}
+	}
+	return s
+}
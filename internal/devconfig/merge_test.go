@@ -0,0 +1,176 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devconfig
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func mustLoad(t *testing.T, content string) *Config {
+	t.Helper()
+	cfg, err := LoadBytes([]byte(content), ".json")
+	if err != nil {
+		t.Fatalf("got error loading config %s: %v", content, err)
+	}
+	return cfg
+}
+
+func TestMerge(t *testing.T) {
+	base := mustLoad(t, `{
+		"packages": ["go@1.21", "ripgrep"],
+		"env": {"A": "project"},
+		"shell": {
+			"init_hook": ["echo project"],
+			"scripts": {"test": "go test ./..."}
+		}
+	}`)
+	first := mustLoad(t, `{
+		"packages": ["go@1.20", "curl"],
+		"env": {"A": "first", "B": "first", "C": "first"},
+		"shell": {
+			"init_hook": "echo first",
+			"scripts": {"lint": "golangci-lint run", "test": "make test"}
+		}
+	}`)
+	second := mustLoad(t, `{
+		"packages": ["curl@8", "jq"],
+		"env": {"B": "second"},
+		"shell": {"init_hook": ["echo second"]}
+	}`)
+
+	merged, err := Merge(
+		base,
+		&IncludedConfig{Config: first, Ref: "path:first.json"},
+		&IncludedConfig{Config: second, Ref: "path:second.json"},
+	)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+
+	wantPackages := []string{"go@1.21", "ripgrep", "curl@8", "jq"}
+	if diff := cmp.Diff(wantPackages, merged.Packages); diff != "" {
+		t.Errorf("wrong packages (-want +got):\n%s", diff)
+	}
+	wantEnv := map[string]string{"A": "project", "B": "second", "C": "first"}
+	if diff := cmp.Diff(wantEnv, merged.Env); diff != "" {
+		t.Errorf("wrong env (-want +got):\n%s", diff)
+	}
+	wantHooks := []string{"echo first", "echo second", "echo project"}
+	if diff := cmp.Diff(wantHooks, merged.InitHook().Cmds); diff != "" {
+		t.Errorf("wrong init hooks (-want +got):\n%s", diff)
+	}
+	if got := merged.Scripts()["test"].String(); got != "go test ./..." {
+		t.Errorf("got test script %q, want project's script", got)
+	}
+	if _, ok := merged.Scripts()["lint"]; !ok {
+		t.Error("included lint script is missing")
+	}
+
+	// The project's config must not be modified.
+	if len(base.Packages) != 2 || len(base.Env) != 1 || len(base.Scripts()) != 1 {
+		t.Errorf("base config was modified: %+v", base)
+	}
+}
+
+func TestMergeNoIncludes(t *testing.T) {
+	base := mustLoad(t, `{"packages": ["go"]}`)
+	merged, err := Merge(base)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if merged != base {
+		t.Error("got a new config, want the project's config")
+	}
+}
+
+func TestMergeScriptConflict(t *testing.T) {
+	first := &IncludedConfig{
+		Config: mustLoad(t, `{"shell": {"scripts": {"build": "make"}}}`),
+		Ref:    "path:first.json",
+	}
+	second := &IncludedConfig{
+		Config: mustLoad(t, `{"shell": {"scripts": {"build": "go build"}}}`),
+		Ref:    "path:second.json",
+	}
+
+	if _, err := Merge(mustLoad(t, `{}`), first, second); err == nil {
+		t.Error("got nil error for conflicting included scripts, want error")
+	}
+
+	// Defining the script in the project resolves the conflict.
+	base := mustLoad(t, `{"shell": {"scripts": {"build": "bazel build"}}}`)
+	merged, err := Merge(base, first, second)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := merged.Scripts()["build"].String(); got != "bazel build" {
+		t.Errorf("got build script %q, want %q", got, "bazel build")
+	}
+}
+
+func TestMergeNestedIncludes(t *testing.T) {
+	nested := &IncludedConfig{
+		Config: mustLoad(t, `{"include": ["path:other.json"]}`),
+		Ref:    "path:nested.json",
+	}
+	if _, err := Merge(mustLoad(t, `{}`), nested); err == nil {
+		t.Error("got nil error for nested includes, want error")
+	}
+}
+
+func TestMergeSubstituters(t *testing.T) {
+	base := mustLoad(t, `{
+		"substituters": ["file:///tmp/cache"],
+		"trusted_public_keys": ["local-1:abc="]
+	}`)
+	included := &IncludedConfig{
+		Config: mustLoad(t, `{
+			"substituters": ["https://cache.example.com", "file:///tmp/cache"],
+			"trusted_public_keys": ["cache.example.com-1:def="]
+		}`),
+		Ref: "path:company.json",
+	}
+
+	merged, err := Merge(base, included)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	wantSubstituters := []string{"file:///tmp/cache", "https://cache.example.com"}
+	if diff := cmp.Diff(wantSubstituters, merged.Substituters); diff != "" {
+		t.Errorf("wrong substituters (-want +got):\n%s", diff)
+	}
+	wantKeys := []string{"local-1:abc=", "cache.example.com-1:def="}
+	if diff := cmp.Diff(wantKeys, merged.TrustedPublicKeys); diff != "" {
+		t.Errorf("wrong trusted public keys (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeServices(t *testing.T) {
+	base := mustLoad(t, `{"services": {"web": "npm start"}}`)
+	included := &IncludedConfig{
+		Config: mustLoad(t, `{
+			"services": {
+				"web": "python -m http.server",
+				"db": {"command": "postgres", "restart": "always"}
+			}
+		}`),
+		Ref: "path:company.json",
+	}
+
+	merged, err := Merge(base, included)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := merged.Services["web"].Command; got != "npm start" {
+		t.Errorf("got web command %q, want project's command", got)
+	}
+	if got := merged.Services["db"]; got == nil || got.Restart != "always" {
+		t.Errorf("got db service %+v, want included service", got)
+	}
+	if len(base.Services) != 1 {
+		t.Errorf("base config was modified: %+v", base.Services)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devconfig
+
+import (
+	_ "embed"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/cuecfg"
+)
+
+// Schema is the JSON Schema of devbox.json. Editors can use it for validation
+// and completion by setting "$schema" in devbox.json. The version in the file
+// name must be bumped on backwards incompatible changes.
+//
+//go:embed schema/devbox.v1.schema.json
+var Schema []byte
+
+// ValidateSchema validates the content of a devbox.json file against Schema.
+// Unknown keys and values of the wrong type are reported with their position
+// in the file.
+func ValidateSchema(data []byte, filename string) error {
+	if err := cuecfg.ValidateJSONSchema(Schema, data, filename); err != nil {
+		return usererr.New("%s", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devconfig
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name: "valid",
+			content: `{
+  "$schema": "devbox.v1.schema.json",
+  "packages": ["go@1.20"],
+  "env": {"FOO": "bar"},
+  "shell": {
+    "init_hook": "echo hello",
+    "scripts": {
+      "build": ["go build ./..."],
+      "test": {"command": "go test ./...", "depends_on": ["build"]},
+      "web": {
+        "description": "Start the web server",
+        "command": "npm start",
+        "env": {"PORT": "8080"},
+        "cwd": "web",
+        "watch": ["web/**/*.js"]
+      }
+    }
+  },
+  "nixpkgs": {"commit": "3364b5b117f65fe1ce65a3cdd5612a078a3b31e3"},
+  "include": ["plugin:php-config"],
+  "services": {
+    "worker": "python worker.py",
+    "web": {
+      "command": "npm start",
+      "env": {"PORT": "8080"},
+      "cwd": "web",
+      "depends_on": ["worker"],
+      "readiness_probe": {"http_get": "http://localhost:8080/health", "period_seconds": 2},
+      "restart": "on_failure"
+    }
+  }
+}`,
+		},
+		{
+			name: "unknown keys",
+			content: `{
+  "packages": [],
+  "shell": {
+    "init_hooks": ["echo hello"]
+  },
+  "scirpts": {}
+}`,
+			want: []string{
+				"devbox.json:4:5: shell: field not allowed: init_hooks",
+				"devbox.json:6:3: field not allowed: scirpts",
+			},
+		},
+		{
+			name: "wrong types",
+			content: `{
+  "packages": "go",
+  "env": {"PORT": 8080},
+  "shell": {"scripts": {"test": true}}
+}`,
+			want: []string{
+				"devbox.json:2:15: packages: conflicting values",
+				"devbox.json:3:19: env.PORT: conflicting values 8080 and string",
+				"devbox.json:4:33: shell.scripts.test: value does not match any of the allowed types",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateSchema([]byte(tc.content), DefaultName)
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatalf("got error for valid config: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("got nil error for invalid config, want error")
+			}
+			problems := strings.Split(err.Error(), "\n")[1:]
+			if len(problems) != len(tc.want) {
+				t.Fatalf("got problems %q, want %q", problems, tc.want)
+			}
+			for i, want := range tc.want {
+				if got := strings.TrimSpace(problems[i]); !strings.HasPrefix(got, want) {
+					t.Errorf("got problem %q, want prefix %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestSchemaAcceptsExamples makes sure that the schema doesn't reject configs
+// that devbox accepts.
+func TestSchemaAcceptsExamples(t *testing.T) {
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "node_modules" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || d.Name() != DefaultName {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := ValidateSchema(data, path); err != nil {
+			t.Error(err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devconfig
+
+import (
+	"encoding/json"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+)
+
+// ServiceConfig is a service declared in the services section of devbox.json.
+// Devbox runs it with process-compose, like the services of plugins and of the
+// project's process-compose.yaml. A service is either a command string or an
+// object, and marshals back to the format it was written in.
+type ServiceConfig struct {
+	// Command is the shell command that runs the service.
+	Command string `json:"command"`
+	// Env sets env variables for the service, in addition to the devbox
+	// environment.
+	Env map[string]string `json:"env,omitempty"`
+	// Cwd is the directory, relative to the project, in which the service
+	// runs. It runs in the project's directory if it's empty.
+	Cwd string `json:"cwd,omitempty"`
+	// DependsOn lists the services that must start before this one. If a
+	// dependency declares a readiness probe, this service waits until the
+	// dependency is ready.
+	DependsOn []string `json:"depends_on,omitempty"`
+	// ReadinessProbe checks whether the service is ready to be used.
+	ReadinessProbe *ProbeConfig `json:"readiness_probe,omitempty"`
+	// Restart is the restart policy of the service: "no" (the default),
+	// "always", "on_failure" or "exit_on_failure".
+	Restart string `json:"restart,omitempty"`
+
+	// isString is true if the service was written as a command string.
+	isString bool
+}
+
+// ProbeConfig is a check that process-compose runs periodically. Exactly one
+// of Exec and HTTPGet is set.
+type ProbeConfig struct {
+	// Exec is a command that succeeds when the check passes.
+	Exec string `json:"exec,omitempty"`
+	// HTTPGet is a URL that returns a 2xx status when the check passes.
+	HTTPGet string `json:"http_get,omitempty"`
+
+	InitialDelaySeconds int `json:"initial_delay_seconds,omitempty"`
+	PeriodSeconds       int `json:"period_seconds,omitempty"`
+	TimeoutSeconds      int `json:"timeout_seconds,omitempty"`
+	FailureThreshold    int `json:"failure_threshold,omitempty"`
+}
+
+// serviceObject is the object format of ServiceConfig. It's a distinct type
+// so that marshalling it doesn't call ServiceConfig.MarshalJSON.
+type serviceObject ServiceConfig
+
+// MarshalJSON marshals a service back to a command string if it was written
+// as one.
+func (s ServiceConfig) MarshalJSON() ([]byte, error) {
+	if s.isString {
+		return cuecfg.MarshalJSON(s.Command)
+	}
+	return cuecfg.MarshalJSON(serviceObject(s))
+}
+
+// UnmarshalJSON unmarshals a service from a command string or an object.
+func (s *ServiceConfig) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*s = ServiceConfig{isString: true}
+		return json.Unmarshal(data, &s.Command)
+	}
+	obj := serviceObject{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*s = ServiceConfig(obj)
+	return nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devconfig
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestServiceConfigRoundTrip(t *testing.T) {
+	for _, data := range []string{
+		`"npm start"`,
+		`{"command":"npm start"}`,
+		`{"command":"postgres","depends_on":["init"],"readiness_probe":{"exec":"pg_isready"},"restart":"always"}`,
+	} {
+		svc := &ServiceConfig{}
+		if err := json.Unmarshal([]byte(data), svc); err != nil {
+			t.Fatalf("got error unmarshalling %s: %v", data, err)
+		}
+		got, err := json.Marshal(svc)
+		if err != nil {
+			t.Fatalf("got error marshalling %s: %v", data, err)
+		}
+		if string(got) != data {
+			t.Errorf("got %s, want %s", got, data)
+		}
+	}
+}
@@ -147,8 +147,11 @@ func (p *Package) URLForFlakeInput() string {
 
 // Installable for this package. Installable is a nix concept defined here:
 // https://nixos.org/manual/nix/stable/command-ref/new-cli/nix.html#installables
+//
+// Packages whose locked store path is substitutable are installed by store
+// path, which skips evaluating nixpkgs.
 func (p *Package) Installable() (string, error) {
-	inCache, err := p.IsInBinaryCache()
+	inCache, err := p.IsSubstitutable()
 	if err != nil {
 		return "", err
 	}
@@ -450,60 +453,153 @@ func (p *Package) HashFromNixPkgsURL() string {
 	return nix.HashFromNixPkgsURL(p.URLForFlakeInput())
 }
 
-// BinaryCache is the store from which to fetch this package's binaries.
-// It is used as FromStore in builtins.fetchClosure.
-const BinaryCache = "https://cache.nixos.org"
+// BinaryCache is the default store from which to fetch packages' binaries.
+// Projects can add their own stores with substituters in devbox.json.
+const BinaryCache = nix.DefaultBinaryCache
 
 func (p *Package) IsInBinaryCache() (bool, error) {
 	if !featureflag.RemoveNixpkgs.Enabled() {
 		return false, nil
 	}
+	cache, err := p.BinaryCache()
+	return cache != "", err
+}
 
+// LockedStorePath returns the store path that the lockfile records for the
+// user's system. It returns false if the package isn't versioned, or if the
+// lockfile has no store path for the user's system.
+func (p *Package) LockedStorePath() (string, bool, error) {
 	if !p.isVersioned() {
-		return false, nil
+		return "", false, nil
 	}
 
 	entry, err := p.lockfile.Resolve(p.Raw)
 	if err != nil {
-		return false, err
+		return "", false, err
 	}
 
 	userSystem, err := nix.System()
 	if err != nil {
-		return false, err
+		return "", false, err
 	}
 
-	if entry.Systems == nil {
-		return false, nil
+	sysInfo := entry.Systems[userSystem]
+	if sysInfo == nil || sysInfo.StorePath == "" {
+		return "", false, nil
 	}
-
-	// Check if the user's system's info is present in the lockfile
-	_, ok := entry.Systems[userSystem]
-	return ok, nil
+	return sysInfo.StorePath, true, nil
 }
 
-// InputAddressedPath is the input-addressed path in /nix/store
-// It is also the key in the BinaryCache for this package
-func (p *Package) InputAddressedPath() (string, error) {
-	if inCache, err := p.IsInBinaryCache(); err != nil {
+// binaryCacheOf memoizes the binary cache that has a store path, or "" if none
+// of them has it, because checking is a network request and packages are
+// created many times.
+var binaryCacheOf = map[string]string{}
+
+// BinaryCache returns the first of the binary caches that has the package's
+// locked store path. It returns "" if the package has no locked store path, or
+// if none of the caches has it.
+func (p *Package) BinaryCache() (string, error) {
+	storePath, ok, err := p.LockedStorePath()
+	if err != nil || !ok {
 		return "", err
-	} else if !inCache {
-		return "",
-			errors.Errorf("Package %q cannot be fetched from binary cache store", p.Raw)
+	}
+	if cache, checked := binaryCacheOf[storePath]; checked {
+		return cache, nil
 	}
 
-	entry, err := p.lockfile.Resolve(p.Raw)
-	if err != nil {
-		return "", err
+	found := ""
+	for _, cache := range p.lockfile.BinaryCaches().URLs() {
+		_, inCache, err := nix.BinaryCachePathInfo(cache, storePath)
+		if err != nil {
+			return "", err
+		}
+		if inCache {
+			found = cache
+			break
+		}
 	}
+	binaryCacheOf[storePath] = found
+	return found, nil
+}
 
-	userSystem, err := nix.System()
+// IsSubstitutable returns true if the package's locked store path is in the
+// local store or in a binary cache, so that it can be installed without
+// evaluating nixpkgs.
+func (p *Package) IsSubstitutable() (bool, error) {
+	storePath, ok, err := p.LockedStorePath()
+	if err != nil || !ok {
+		return false, err
+	}
+	if _, err := os.Stat(storePath); err == nil {
+		return true, nil
+	}
+	cache, err := p.BinaryCache()
+	return cache != "", err
+}
+
+// SubstituteFromBinaryCache copies the locked store paths of the packages
+// from their binary caches to the local store, with one nix call per cache, so
+// that they can be installed without evaluating nixpkgs. Packages that aren't
+// substitutable are skipped. If a copy fails, the packages whose paths didn't
+// make it to the local store are no longer considered substitutable, and are
+// installed from their flake reference instead.
+func SubstituteFromBinaryCache(w io.Writer, caches nix.BinaryCaches, pkgs []*Package) error {
+	storePathsByCache := map[string][]string{}
+	cacheURLs := []string{}
+	for _, pkg := range pkgs {
+		storePath, ok, err := pkg.LockedStorePath()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(storePath); err == nil {
+			continue
+		}
+		cache, err := pkg.BinaryCache()
+		if err != nil {
+			return err
+		}
+		if cache == "" {
+			continue
+		}
+		if _, ok := storePathsByCache[cache]; !ok {
+			cacheURLs = append(cacheURLs, cache)
+		}
+		storePathsByCache[cache] = append(storePathsByCache[cache], storePath)
+	}
+
+	for _, cache := range cacheURLs {
+		storePaths := storePathsByCache[cache]
+		if err := nix.CopyFromBinaryCache(w, caches, cache, storePaths...); err != nil {
+			ux.Fwarning(
+				w,
+				"failed to fetch packages from %s, falling back to evaluating them: %v\n",
+				cache,
+				err,
+			)
+			for _, storePath := range storePaths {
+				if _, err := os.Stat(storePath); err != nil {
+					binaryCacheOf[storePath] = ""
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// InputAddressedPath is the input-addressed path in /nix/store
+// It is also the key in the BinaryCache for this package
+func (p *Package) InputAddressedPath() (string, error) {
+	storePath, ok, err := p.LockedStorePath()
 	if err != nil {
 		return "", err
+	} else if !ok {
+		return "",
+			errors.Errorf("Package %q cannot be fetched from binary cache store", p.Raw)
 	}
-
-	sysInfo := entry.Systems[userSystem]
-	return sysInfo.StorePath, nil
+	return storePath, nil
 }
 
 // ContentAddressedPath is the content-addressed form of Package.InputAddressedPath
@@ -107,6 +107,10 @@ type lockfile struct {
 	projectDir string
 }
 
+func (l *lockfile) BinaryCaches() nix.BinaryCaches {
+	return nix.BinaryCaches{}
+}
+
 func (l *lockfile) ProjectDir() string {
 	return l.projectDir
 }
@@ -179,3 +183,71 @@ func TestHashFromNixPkgsURL(t *testing.T) {
 		}
 	}
 }
+
+type storePathLockfile struct {
+	lockfile
+	packages map[string]*lock.Package
+}
+
+func (l *storePathLockfile) Resolve(pkg string) (*lock.Package, error) {
+	if entry, ok := l.packages[pkg]; ok {
+		return entry, nil
+	}
+	return l.lockfile.Resolve(pkg)
+}
+
+func TestInstallableFromLockedStorePath(t *testing.T) {
+	t.Setenv("__DEVBOX_NIX_SYSTEM", "x86_64-linux")
+
+	// A path in the "local store", and one that is neither local nor in the
+	// binary cache.
+	localPath := t.TempDir()
+	missingPath := filepath.Join(t.TempDir(), "missing")
+	binaryCacheOf[missingPath] = ""
+	t.Cleanup(func() { delete(binaryCacheOf, missingPath) })
+
+	resolved := "github:NixOS/nixpkgs/" + nixCommitHash + "#hello"
+	locker := &storePathLockfile{packages: map[string]*lock.Package{
+		"hello@1.0": {
+			Resolved: resolved,
+			Systems:  map[string]*lock.SystemInfo{"x86_64-linux": {StorePath: localPath}},
+		},
+		"hello@2.0": {
+			Resolved: resolved,
+			Systems:  map[string]*lock.SystemInfo{"x86_64-linux": {StorePath: missingPath}},
+		},
+		"hello@3.0": {
+			Resolved: resolved,
+			Systems:  map[string]*lock.SystemInfo{"aarch64-darwin": {StorePath: localPath}},
+		},
+	}}
+
+	tests := []struct {
+		pkg         string
+		locked      bool
+		installable string
+	}{
+		{pkg: "hello@1.0", locked: true, installable: localPath},
+		{pkg: "hello@2.0", locked: true, installable: resolved},
+		{pkg: "hello@3.0", locked: false, installable: resolved},
+	}
+	for _, test := range tests {
+		t.Run(test.pkg, func(t *testing.T) {
+			pkg := PackageFromString(test.pkg, locker)
+			_, locked, err := pkg.LockedStorePath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if locked != test.locked {
+				t.Errorf("LockedStorePath() locked = %v, want %v", locked, test.locked)
+			}
+			installable, err := pkg.Installable()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if installable != test.installable {
+				t.Errorf("Installable() = %q, want %q", installable, test.installable)
+			}
+		})
+	}
+}
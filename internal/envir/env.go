@@ -13,6 +13,8 @@ const (
 	DevboxLatestVersion  = "DEVBOX_LATEST_VERSION"
 	DevboxRegion         = "DEVBOX_REGION"
 	DevboxSearchHost     = "DEVBOX_SEARCH_HOST"
+	DevboxSearchIndex    = "DEVBOX_SEARCH_INDEX"
+	DevboxSearchOffline  = "DEVBOX_SEARCH_OFFLINE"
 	DevboxShellEnabled   = "DEVBOX_SHELL_ENABLED"
 	DevboxShellStartTime = "DEVBOX_SHELL_START_TIME"
 	DevboxVM             = "DEVBOX_VM"
@@ -0,0 +1,38 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package fileutil
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// GitIgnorePatterns returns the patterns in the .gitignore file of dir, without
+// comments and blank lines. It returns no patterns if dir has no .gitignore.
+//
+// It only looks at the .gitignore file in dir itself, not the ones in its
+// ancestor or child directories.
+func GitIgnorePatterns(dir string) ([]string, error) {
+	contents, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, pkgerrors.WithStack(err)
+	}
+
+	patterns := []string{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") && line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, nil
+}
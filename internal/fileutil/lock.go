@@ -0,0 +1,36 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// LockFile takes an exclusive advisory lock on file, waiting for other
+// processes that hold it for at most timeout. The lock is released when the
+// file is closed. The file is closed if the lock can't be taken.
+func LockFile(file *os.File, timeout time.Duration) error {
+	lockResult := make(chan error)
+
+	go func() {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		lockResult <- err
+	}()
+
+	select {
+	case err := <-lockResult:
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to lock file: %w", err)
+		}
+		return nil
+
+	case <-time.After(timeout):
+		file.Close()
+		return fmt.Errorf("lock of %s timed out after %d seconds", file.Name(), timeout/time.Second)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime/trace"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/nix/nixcache"
+	"go.jetpack.io/devbox/internal/nix/nixstore"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+// nixStoreDir is the directory of the local nix store.
+const nixStoreDir = "/nix/store"
+
+// CachePush installs the project's packages and uploads the closure of its nix
+// profile to a binary cache, so that CI and other developers can fetch the
+// packages instead of building them. The profile includes the outputs of the
+// project's local flakes. Paths that the cache already has are skipped.
+func (d *Devbox) CachePush(ctx context.Context, cacheURL string) error {
+	ctx, task := trace.NewTask(ctx, "devboxCachePush")
+	defer task.End()
+
+	cache, err := nixcache.Open(ctx, cacheURL)
+	if err != nil {
+		return err
+	}
+	if err := d.ensurePackagesAreInstalled(ctx, ensure); err != nil {
+		return err
+	}
+
+	store, err := nixstore.Local(nixStoreDir)
+	if err != nil {
+		return err
+	}
+	closure, err := profileClosure(store, d.projectDir)
+	if err != nil {
+		return err
+	}
+	missing := []string{}
+	for _, pkg := range closure {
+		storePath := filepath.Join(nixStoreDir, pkg.StoreName)
+		has, err := cache.Has(ctx, storePath)
+		if err != nil {
+			return err
+		}
+		if !has {
+			missing = append(missing, storePath)
+		}
+	}
+
+	if len(missing) == 0 {
+		ux.Fsuccess(d.writer, "%s already has all %d paths of the project\n", cacheURL, len(closure))
+		return nil
+	}
+	fmt.Fprintf(
+		d.writer,
+		"Pushing %d of %d paths to %s\n",
+		len(missing),
+		len(closure),
+		cacheURL,
+	)
+	if err := nix.CopyToBinaryCache(d.writer, cacheURL, missing...); err != nil {
+		return err
+	}
+	ux.Fsuccess(d.writer, "Pushed %d paths to %s\n", len(missing), cacheURL)
+	return nil
+}
+
+// profileClosure returns the packages of the nix profile of the project in
+// projectDir, and everything they depend on, with dependencies before the
+// packages that depend on them. It returns nil if the project has no profile.
+// Indexing the store is slow, so callers that need several closures should
+// reuse the store.
+func profileClosure(store *nixstore.Root, projectDir string) ([]*nixstore.Package, error) {
+	// The profile is a chain of symlinks that ends in the store.
+	profileStorePath, err := filepath.EvalSymlinks(filepath.Join(projectDir, nix.ProfilePath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if filepath.Dir(profileStorePath) != nixStoreDir {
+		return nil, errors.Errorf("profile %s is not in %s", profileStorePath, nixStoreDir)
+	}
+
+	pkg, err := store.Package(filepath.Base(profileStorePath))
+	if err != nil {
+		return nil, err
+	}
+	return nixstore.TopologicalSort(pkg), nil
+}
@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
@@ -23,6 +24,7 @@ import (
 	"go.jetpack.io/devbox/internal/impl/generate"
 	"go.jetpack.io/devbox/internal/shellgen"
 	"go.jetpack.io/devbox/internal/telemetry"
+	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
@@ -53,12 +55,18 @@ const (
 
 type Devbox struct {
 	cfg           *devconfig.Config
+	includedCfgs  []*devconfig.IncludedConfig
+	effectiveCfg  *devconfig.Config
 	lockfile      *lock.File
 	nix           nix.Nixer
 	projectDir    string
 	pluginManager *plugin.Manager
 	pure          bool
 
+	// uncachedIncludes are the remote includes that weren't loaded because
+	// devopt.Opts.CachedIncludes is set and they aren't cached.
+	uncachedIncludes []string
+
 	// Possible TODO: hardcode this to stderr. Allowing the caller to specify the
 	// writer is error prone. Since it is almost always stderr, we should default
 	// it and if the user wants stdout then they can return a string and print it.
@@ -99,6 +107,19 @@ func Open(opts *devopt.Opts) (*Devbox, error) {
 	)
 	box.lockfile = lock
 
+	if opts.CachedIncludes {
+		box.includedCfgs, box.uncachedIncludes, err =
+			box.pluginManager.CachedIncludedConfigs(cfg.Include)
+	} else {
+		box.includedCfgs, err = box.pluginManager.IncludedConfigs(cfg.Include)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := box.mergeConfigs(); err != nil {
+		return nil, err
+	}
+
 	if !opts.IgnoreWarnings &&
 		!legacyPackagesWarningHasBeenShown &&
 		box.HasDeprecatedPackages() {
@@ -126,10 +147,28 @@ func (d *Devbox) Config() *devconfig.Config {
 	return d.cfg
 }
 
+// EffectiveConfig returns the project's config merged with the configs it
+// includes. See devconfig.Merge for how configs are merged. Use Config to read
+// or modify the project's devbox.json.
+func (d *Devbox) EffectiveConfig() *devconfig.Config {
+	return d.effectiveCfg
+}
+
+// mergeConfigs merges the project's config with the configs it includes into
+// the effective config. It must be called again whenever d.cfg changes.
+func (d *Devbox) mergeConfigs() error {
+	cfg, err := devconfig.Merge(d.cfg, d.includedCfgs...)
+	if err != nil {
+		return err
+	}
+	d.effectiveCfg = cfg
+	return nil
+}
+
 func (d *Devbox) ConfigHash() (string, error) {
 	pkgHashes := lo.Map(d.PackagesAsInputs(), func(i *devpkg.Package, _ int) string { return i.Hash() })
 	includeHashes := lo.Map(d.Includes(), func(i plugin.Includable, _ int) string { return i.Hash() })
-	h, err := d.cfg.Hash()
+	h, err := d.EffectiveConfig().Hash()
 	if err != nil {
 		return "", err
 	}
@@ -139,7 +178,7 @@ func (d *Devbox) ConfigHash() (string, error) {
 }
 
 func (d *Devbox) NixPkgsCommitHash() string {
-	return d.cfg.NixPkgsCommitHash()
+	return d.EffectiveConfig().NixPkgsCommitHash()
 }
 
 func (d *Devbox) Generate(ctx context.Context) error {
@@ -153,6 +192,7 @@ func (d *Devbox) Shell(ctx context.Context) error {
 	ctx, task := trace.NewTask(ctx, "devboxShell")
 	defer task.End()
 
+	recordProject(d.projectDir)
 	if err := d.ensurePackagesAreInstalled(ctx, ensure); err != nil {
 		return err
 	}
@@ -199,34 +239,44 @@ func (d *Devbox) RunScript(ctx context.Context, cmdName string, cmdArgs []string
 	ctx, task := trace.NewTask(ctx, "devboxRun")
 	defer task.End()
 
-	if err := d.ensurePackagesAreInstalled(ctx, ensure); err != nil {
+	env, err := d.prepareRun(ctx)
+	if err != nil {
 		return err
 	}
 
-	if err := shellgen.WriteScriptsToFiles(d); err != nil {
-		return err
+	// wrap the arg in double-quotes, and escape any double-quotes inside it
+	for idx, arg := range cmdArgs {
+		cmdArgs[idx] = strconv.Quote(arg)
 	}
 
-	env, err := d.nixEnv(ctx)
-	if err != nil {
-		return err
+	if _, ok := d.EffectiveConfig().Scripts()[cmdName]; ok {
+		deps, err := d.scriptDependencies(cmdName)
+		if err != nil {
+			return err
+		}
+		if err := d.runScriptsInOrder(deps, env); err != nil {
+			return err
+		}
 	}
-	// Used to determine whether we're inside a shell (e.g. to prevent shell inception)
-	// This is temporary because StartServices() needs it but should be replaced with
-	// better alternative since devbox run and devbox shell are not the same.
-	env["DEVBOX_SHELL_ENABLED"] = "1"
 
-	if err = wrapnix.CreateWrappers(ctx, d); err != nil {
+	cmdWithArgs, env, err := d.commandLine(cmdName, cmdArgs, env)
+	if err != nil {
 		return err
 	}
+	return nix.RunScript(d.projectDir, cmdWithArgs, env)
+}
 
-	// wrap the arg in double-quotes, and escape any double-quotes inside it
-	for idx, arg := range cmdArgs {
-		cmdArgs[idx] = strconv.Quote(arg)
-	}
-
+// commandLine returns the command line that runs a script or an arbitrary
+// command, and the env it runs with. It doesn't run the dependencies of
+// scripts. cmdArgs must already be quoted.
+func (d *Devbox) commandLine(
+	cmdName string,
+	cmdArgs []string,
+	env map[string]string,
+) (string, map[string]string, error) {
 	var cmdWithArgs []string
-	if _, ok := d.cfg.Scripts()[cmdName]; ok {
+	if _, ok := d.EffectiveConfig().Scripts()[cmdName]; ok {
+		env = d.scriptEnv(cmdName, env)
 		// it's a script, so replace the command with the script file's path.
 		cmdWithArgs = append([]string{shellgen.ScriptPath(d.ProjectDir(), cmdName)}, cmdArgs...)
 	} else {
@@ -237,13 +287,39 @@ func (d *Devbox) RunScript(ctx context.Context, cmdName string, cmdArgs []string
 		// we save the entire command (with args) into the DEVBOX_RUN_CMD var, and then the script evals it.
 		err := shellgen.WriteScriptFile(d, arbitraryCmdFilename, shellgen.ScriptBody(d, "eval $DEVBOX_RUN_CMD\n"))
 		if err != nil {
-			return err
+			return "", nil, err
 		}
 		cmdWithArgs = []string{shellgen.ScriptPath(d.ProjectDir(), arbitraryCmdFilename)}
 		env["DEVBOX_RUN_CMD"] = strings.Join(append([]string{cmdName}, cmdArgs...), " ")
 	}
+	return strings.Join(cmdWithArgs, " "), env, nil
+}
+
+// prepareRun installs the project's packages and writes its scripts, and
+// returns the environment in which scripts and commands run.
+func (d *Devbox) prepareRun(ctx context.Context) (map[string]string, error) {
+	recordProject(d.projectDir)
+	if err := d.ensurePackagesAreInstalled(ctx, ensure); err != nil {
+		return nil, err
+	}
+
+	if err := shellgen.WriteScriptsToFiles(d); err != nil {
+		return nil, err
+	}
 
-	return nix.RunScript(d.projectDir, strings.Join(cmdWithArgs, " "), env)
+	env, err := d.nixEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Used to determine whether we're inside a shell (e.g. to prevent shell inception)
+	// This is temporary because StartServices() needs it but should be replaced with
+	// better alternative since devbox run and devbox shell are not the same.
+	env["DEVBOX_SHELL_ENABLED"] = "1"
+
+	if err = wrapnix.CreateWrappers(ctx, d); err != nil {
+		return nil, err
+	}
+	return env, nil
 }
 
 // Install ensures that all the packages in the config are installed and
@@ -253,20 +329,17 @@ func (d *Devbox) Install(ctx context.Context) error {
 	ctx, task := trace.NewTask(ctx, "devboxInstall")
 	defer task.End()
 
+	recordProject(d.projectDir)
 	if _, err := d.PrintEnv(ctx, false /*includeHooks*/); err != nil {
 		return err
 	}
 	return wrapnix.CreateWrappers(ctx, d)
 }
 
+// ListScripts returns the names of the project's scripts, sorted.
 func (d *Devbox) ListScripts() []string {
-	scripts := d.cfg.Scripts()
-	keys := make([]string, len(scripts))
-	i := 0
-	for k := range scripts {
-		keys[i] = k
-		i++
-	}
+	keys := lo.Keys(d.EffectiveConfig().Scripts())
+	slices.Sort(keys)
 	return keys
 }
 
@@ -475,6 +548,7 @@ func (d *Devbox) Services() (services.Services, error) {
 	pluginSvcs, err := d.pluginManager.GetServices(
 		d.PackagesAsInputs(),
 		d.cfg.Include,
+		d.EffectiveConfig().Services,
 	)
 	if err != nil {
 		return nil, err
@@ -545,7 +619,11 @@ func (d *Devbox) StopServices(ctx context.Context, allProjects bool, serviceName
 	}
 
 	if allProjects {
-		return services.StopAllProcessManagers(ctx, d.writer)
+		dirs, err := serviceProjectDirs()
+		if err != nil {
+			return err
+		}
+		return services.StopAllProcessManagers(ctx, d.writer, dirs)
 	}
 
 	if !services.ProcessManagerIsRunning(d.projectDir) {
@@ -573,42 +651,64 @@ func (d *Devbox) StopServices(ctx context.Context, allProjects bool, serviceName
 	return nil
 }
 
-func (d *Devbox) ListServices(ctx context.Context) error {
-	if !d.IsEnvEnabled() {
-		return d.RunScript(ctx, "devbox", []string{"services", "ls"})
+// PrintProcessManagers prints the running process-compose instances of the
+// project, or of all the projects that devbox knows about if allProjects is
+// true.
+func (d *Devbox) PrintProcessManagers(ctx context.Context, allProjects bool) error {
+	dirs := []string{d.projectDir}
+	if allProjects {
+		var err error
+		if dirs, err = serviceProjectDirs(); err != nil {
+			return err
+		}
+	}
+	instances := services.RunningInstances(d.writer, dirs)
+	if len(instances) == 0 {
+		fmt.Fprintln(d.writer, "No process-compose instances are running")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(d.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tPID\tPORT\tSTARTED")
+	for _, inst := range instances {
+		fmt.Fprintf(
+			tw,
+			"%s\t%d\t%d\t%s\n",
+			inst.ProjectDir,
+			inst.Pid,
+			inst.Port,
+			orDash(!inst.Started.IsZero(), inst.Started.Local().Format(time.DateTime)),
+		)
 	}
+	return errors.WithStack(tw.Flush())
+}
 
+// ServiceLogs prints the logs of the project's services. The logs of each
+// service are kept in .devbox/logs, so they can be read after process-compose
+// stops.
+func (d *Devbox) ServiceLogs(ctx context.Context, opts devopt.ServiceLogsOpts) error {
 	svcSet, err := d.Services()
 	if err != nil {
 		return err
 	}
-
 	if len(svcSet) == 0 {
-		fmt.Fprintln(d.writer, "No services found in your project")
-		return nil
+		return usererr.New("No services found in your project")
 	}
 
-	if !services.ProcessManagerIsRunning(d.projectDir) {
-		fmt.Fprintln(d.writer, "No services currently running. Run `devbox services up` to start them:")
-		fmt.Fprintln(d.writer, "")
-		for _, s := range svcSet {
-			fmt.Fprintf(d.writer, "  %s\n", s.Name)
+	names := opts.Services
+	for _, name := range names {
+		if _, ok := svcSet[name]; !ok {
+			return usererr.New("Service %s not found in your project", name)
 		}
-		return nil
 	}
-	tw := tabwriter.NewWriter(d.writer, 3, 2, 8, ' ', tabwriter.TabIndent)
-	pcSvcs, err := services.ListServices(ctx, d.projectDir, d.writer)
-	if err != nil {
-		fmt.Fprintln(d.writer, "Error listing services: ", err)
-	} else {
-		fmt.Fprintln(d.writer, "Services running in process-compose:")
-		fmt.Fprintln(tw, "NAME\tSTATUS\tEXIT CODE")
-		for _, s := range pcSvcs {
-			fmt.Fprintf(tw, "%s\t%s\t%d\n", s.Name, s.Status, s.ExitCode)
-		}
-		tw.Flush()
+	if len(names) == 0 {
+		names = maps.Keys(svcSet)
+		slices.Sort(names)
 	}
-	return nil
+	return services.PrintLogs(ctx, d.writer, d.projectDir, names, services.LogsOpts{
+		Follow: opts.Follow,
+		Since:  opts.Since,
+	})
 }
 
 func (d *Devbox) RestartServices(ctx context.Context, serviceNames ...string) error {
@@ -649,6 +749,8 @@ func (d *Devbox) StartProcessManager(
 	background bool,
 	processComposeFileOrDir string,
 ) error {
+	recordProject(d.projectDir)
+
 	svcs, err := d.Services()
 	if err != nil {
 		return err
@@ -703,6 +805,54 @@ func (d *Devbox) StartProcessManager(
 	)
 }
 
+// WaitForServices waits until process-compose reports that the services are
+// ready, or all of its services if none are given.
+func (d *Devbox) WaitForServices(ctx context.Context, serviceNames []string, timeout time.Duration) error {
+	return services.WaitForServices(ctx, d.writer, d.projectDir, serviceNames, timeout)
+}
+
+// RunScriptWithServices starts services in the background, waits until they
+// are ready and runs a script or command. If process-compose is already
+// running, the named services that it isn't running are started, and only
+// those are stopped after the script. Otherwise, process-compose is stopped
+// after the script. Either way, they're stopped even if the script fails. The
+// script's error is returned as is, so that devbox exits with its exit code.
+func (d *Devbox) RunScriptWithServices(
+	ctx context.Context,
+	serviceNames []string,
+	timeout time.Duration,
+	cmdName string,
+	cmdArgs []string,
+) error {
+	if !services.ProcessManagerIsRunning(d.projectDir) {
+		if err := d.StartProcessManager(ctx, serviceNames, true /*background*/, ""); err != nil {
+			return err
+		}
+		defer func() {
+			if stopErr := services.StopProcessManager(ctx, d.projectDir, d.writer); stopErr != nil {
+				ux.Fwarning(d.writer, "failed to stop the services: %v\n", stopErr)
+			}
+		}()
+	} else {
+		started, err := services.StartStoppedServices(ctx, d.writer, d.projectDir, serviceNames)
+		defer func() {
+			for _, name := range started {
+				if stopErr := services.StopServices(ctx, name, d.projectDir, d.writer); stopErr != nil {
+					ux.Fwarning(d.writer, "failed to stop service %s: %v\n", name, stopErr)
+				}
+			}
+		}()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := d.WaitForServices(ctx, serviceNames, timeout); err != nil {
+		return err
+	}
+	return d.RunScript(ctx, cmdName, cmdArgs)
+}
+
 // computeNixEnv computes the set of environment variables that define a Devbox
 // environment. The "devbox run" and "devbox shell" commands source these
 // variables into a shell before executing a command or showing an interactive
@@ -761,6 +911,7 @@ func (d *Devbox) computeNixEnv(ctx context.Context, usePrintDevEnvCache bool) (m
 	}
 
 	vaf, err := d.nix.PrintDevEnv(ctx, &nix.PrintDevEnvArgs{
+		BinaryCaches:         d.BinaryCaches(),
 		FlakesFilePath:       d.nixFlakesFilePath(),
 		PrintDevEnvCachePath: d.nixPrintDevEnvCachePath(),
 		UsePrintDevEnvCache:  usePrintDevEnvCache,
@@ -913,9 +1064,10 @@ func (d *Devbox) nixFlakesFilePath() string {
 	return filepath.Join(d.projectDir, ".devbox/gen/flake/flake.nix")
 }
 
-// Packages returns the list of Packages to be installed in the nix shell.
+// Packages returns the list of Packages to be installed in the nix shell,
+// including packages from included configs.
 func (d *Devbox) Packages() []string {
-	return d.cfg.Packages
+	return d.EffectiveConfig().Packages
 }
 
 func (d *Devbox) PackagesAsInputs() []*devpkg.Package {
@@ -932,6 +1084,21 @@ func (d *Devbox) Includes() []plugin.Includable {
 	return includes
 }
 
+// BinaryCaches returns the substituters and trusted public keys of the
+// project and its includes.
+func (d *Devbox) BinaryCaches() nix.BinaryCaches {
+	cfg := d.EffectiveConfig()
+	return nix.BinaryCaches{
+		Substituters:      cfg.Substituters,
+		TrustedPublicKeys: cfg.TrustedPublicKeys,
+	}
+}
+
+// IncludeRefs returns the includes as written in devbox.json.
+func (d *Devbox) IncludeRefs() []string {
+	return d.cfg.Include
+}
+
 func (d *Devbox) HasDeprecatedPackages() bool {
 	for _, pkg := range d.PackagesAsInputs() {
 		if pkg.IsLegacy() {
@@ -998,7 +1165,7 @@ func (d *Devbox) checkOldEnvrc() error {
 // allow env variables from outside the shell to be referenced so
 // no leaked variables are caused by this function.
 func (d *Devbox) configEnvs(computedEnv map[string]string) map[string]string {
-	return conf.OSExpandEnvMap(d.cfg.Env, computedEnv, d.ProjectDir())
+	return conf.OSExpandEnvMap(d.EffectiveConfig().Env, computedEnv, d.ProjectDir())
 }
 
 // ignoreCurrentEnvVar contains environment variables that Devbox should remove
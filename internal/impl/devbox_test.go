@@ -12,8 +12,11 @@ import (
 	"testing"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/f1bonacc1/process-compose/src/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v3"
 
 	"go.jetpack.io/devbox/internal/devconfig"
 	"go.jetpack.io/devbox/internal/envir"
@@ -145,3 +148,45 @@ func TestComputeNixPathWhenRemoving(t *testing.T) {
 
 	assert.NotEqual(t, path, path2, "path should not be the same")
 }
+
+func TestServicesFromIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.json": `{
+			"services": {
+				"db": {"command": "postgres", "readiness_probe": {"exec": "pg_isready"}},
+				"cache": "redis-server"
+			}
+		}`,
+		"devbox.json": `{
+			"include": ["path:base.json"],
+			"services": {
+				"web": {"command": "npm start", "depends_on": ["db"]},
+				"cache": "redis-server --port 6380"
+			}
+		}`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	d, err := Open(&devopt.Opts{Dir: dir, Writer: os.Stdout})
+	require.NoError(t, err, "Open should not fail")
+
+	svcs, err := d.Services()
+	require.NoError(t, err, "Services should not fail")
+	assert.ElementsMatch(t, []string{"cache", "db", "web"}, maps.Keys(svcs))
+
+	data, err := os.ReadFile(svcs["web"].ProcessComposePath)
+	require.NoError(t, err, "the process-compose file should be generated")
+	project := &types.Project{}
+	require.NoError(t, yaml.Unmarshal(data, project))
+	// The project's services override services of includes.
+	assert.Equal(t, "redis-server --port 6380", project.Processes["cache"].Command)
+	assert.NotNil(t, project.Processes["db"].ReadinessProbe, "db should have a readiness probe")
+	// Services of the project can wait for services of includes to be ready.
+	assert.Equal(
+		t,
+		types.ProcessConditionHealthy,
+		project.Processes["web"].DependsOn["db"].Condition,
+	)
+}
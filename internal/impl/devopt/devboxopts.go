@@ -2,11 +2,34 @@ package devopt
 
 import (
 	"io"
+	"time"
 )
 
 type Opts struct {
 	Dir            string
 	Pure           bool
 	IgnoreWarnings bool
+	// CachedIncludes only loads remote includes whose locked content is
+	// cached, so that Open doesn't access the network or change the lockfile.
+	CachedIncludes bool
 	Writer         io.Writer
 }
+
+type GCOpts struct {
+	// DryRun prints what would be deleted without deleting it.
+	DryRun bool
+	// Store also runs `nix store gc`, which deletes the store paths that
+	// nothing uses anymore.
+	Store bool
+}
+
+type ServiceLogsOpts struct {
+	// Services are the services to print the logs of. All the services of
+	// the project if it's empty.
+	Services []string
+	// Follow prints new lines as the services write them.
+	Follow bool
+	// Since also prints the logs of the previous runs of the services that
+	// were written to within this duration.
+	Since time.Duration
+}
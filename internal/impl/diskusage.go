@@ -0,0 +1,272 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/trace"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/impl/devopt"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/nix/nixstore"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+// devboxStateDir is the directory of a project where devbox keeps its state:
+// the nix profile, virtenvs, generated files and caches.
+const devboxStateDir = ".devbox"
+
+// diskUsage is the disk space used by the projects that devbox knows about.
+type diskUsage struct {
+	Projects []projectDiskUsage `json:"projects"`
+	// StateSize is the total size of the .devbox directories.
+	StateSize int64 `json:"state_size"`
+	// ClosureSize is the total size of the packages of all projects. Unlike
+	// the sum of the projects' closure sizes, it counts each store path once,
+	// even if several projects use it.
+	ClosureSize int64 `json:"closure_size"`
+}
+
+type projectDiskUsage struct {
+	Dir      string `json:"dir"`
+	Exists   bool   `json:"exists"`
+	Global   bool   `json:"global,omitempty"`
+	LastUsed string `json:"last_used,omitempty"`
+	// StateSize is the size of the project's .devbox directory, without the
+	// store paths that its nix profile links to.
+	StateSize int64 `json:"state_size"`
+	// ClosureSize is the size of the store paths of the project's nix profile
+	// and everything they depend on.
+	ClosureSize  int64 `json:"closure_size"`
+	ClosurePaths int   `json:"closure_paths"`
+	Generations  int   `json:"generations"`
+}
+
+// PrintDiskUsage prints the disk space used by each project that devbox knows
+// about: the recently used projects and the global profile.
+func PrintDiskUsage(ctx context.Context, w io.Writer, asJSON bool) error {
+	_, task := trace.NewTask(ctx, "devboxDiskUsage")
+	defer task.End()
+
+	usage, err := computeDiskUsage()
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		data, err := cuecfg.MarshalJSON(usage)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(usage.print(w))
+}
+
+func computeDiskUsage() (*diskUsage, error) {
+	projects, err := knownProjects()
+	if err != nil {
+		return nil, err
+	}
+	store, err := nixstore.Local(nixStoreDir)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &diskUsage{Projects: []projectDiskUsage{}}
+	// packageSizes caches the size of each store path, because projects
+	// usually share many of them.
+	packageSizes := map[string]int64{}
+	for _, project := range projects {
+		projectUsage := projectDiskUsage{
+			Dir:    project.Dir,
+			Exists: project.Exists,
+			Global: project.Global,
+		}
+		if !project.LastUsed.IsZero() {
+			projectUsage.LastUsed = project.LastUsed.Format(time.RFC3339)
+		}
+		if projectUsage.StateSize, err = dirSize(filepath.Join(project.Dir, devboxStateDir)); err != nil {
+			return nil, err
+		}
+		generations, err := nix.ProfileGenerations(filepath.Join(project.Dir, nix.ProfilePath))
+		if err != nil {
+			return nil, err
+		}
+		projectUsage.Generations = len(generations)
+
+		closure, err := profileClosure(store, project.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range closure {
+			size, ok := packageSizes[pkg.StoreName]
+			if !ok {
+				if size, err = pkg.Size(); err != nil {
+					return nil, err
+				}
+				packageSizes[pkg.StoreName] = size
+				usage.ClosureSize += size
+			}
+			projectUsage.ClosureSize += size
+		}
+		projectUsage.ClosurePaths = len(closure)
+
+		usage.StateSize += projectUsage.StateSize
+		usage.Projects = append(usage.Projects, projectUsage)
+	}
+	return usage, nil
+}
+
+func (u *diskUsage) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tSTATE\tPACKAGES\tGENERATIONS")
+	for _, project := range u.Projects {
+		dir := project.Dir
+		if project.Global {
+			dir += " (global)"
+		} else if !project.Exists {
+			dir += " (deleted)"
+		}
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s (%d paths)\t%d\n",
+			dir,
+			formatSize(project.StateSize),
+			formatSize(project.ClosureSize),
+			project.ClosurePaths,
+			project.Generations,
+		)
+	}
+	fmt.Fprintf(tw, "Total\t%s\t%s\n", formatSize(u.StateSize), formatSize(u.ClosureSize))
+	return tw.Flush()
+}
+
+// GC reclaims the disk space used by the projects that devbox knows about. It
+// deletes the old generations of the projects' nix profiles, and the .devbox
+// directories of projects whose devbox.json was deleted. Deleted projects are
+// then forgotten.
+func GC(ctx context.Context, w io.Writer, opts devopt.GCOpts) error {
+	_, task := trace.NewTask(ctx, "devboxGC")
+	defer task.End()
+
+	projects, err := knownProjects()
+	if err != nil {
+		return err
+	}
+
+	verb := "Removed"
+	if opts.DryRun {
+		verb = "Would remove"
+	}
+	var reclaimed int64
+	forgotten := []string{}
+	for _, project := range projects {
+		if !project.Exists && !project.Global {
+			stateDir := filepath.Join(project.Dir, devboxStateDir)
+			if fileutil.IsDir(stateDir) {
+				size, err := dirSize(stateDir)
+				if err != nil {
+					return err
+				}
+				if !opts.DryRun {
+					if err := os.RemoveAll(stateDir); err != nil {
+						return errors.WithStack(err)
+					}
+				}
+				reclaimed += size
+				fmt.Fprintf(w, "%s %s of a deleted project (%s)\n", verb, stateDir, formatSize(size))
+			}
+			forgotten = append(forgotten, project.Dir)
+			continue
+		}
+
+		profilePath := filepath.Join(project.Dir, nix.ProfilePath)
+		generations, err := nix.ProfileGenerations(profilePath)
+		if err != nil {
+			return err
+		}
+		if len(generations) <= 1 {
+			continue
+		}
+		if !opts.DryRun {
+			if err := nix.ProfileWipeHistory(profilePath); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(w, "%s %d old profile generation(s) of %s\n", verb, len(generations)-1, project.Dir)
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+	// Only the deleted projects are forgotten, so that projects recorded by
+	// other devbox commands in the meantime are kept.
+	err = updateRecentProjects(func(recent recentProjects) bool {
+		for _, dir := range forgotten {
+			delete(recent, dir)
+		}
+		return len(forgotten) > 0
+	})
+	if err != nil {
+		return err
+	}
+	if reclaimed > 0 {
+		ux.Fsuccess(w, "Reclaimed %s of devbox state\n", formatSize(reclaimed))
+	}
+	if !opts.Store {
+		ux.Finfo(w, "Run `devbox gc --store` to also delete the packages that are no longer used.\n")
+		return nil
+	}
+	return nix.StoreGC(w)
+}
+
+// dirSize returns the total size of the regular files in dir, without
+// following symlinks. It returns 0 if dir doesn't exist.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dir {
+			return fs.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, errors.WithStack(err)
+}
+
+// formatSize formats a number of bytes with a binary unit, like du -h.
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"go.jetpack.io/devbox/internal/devconfig"
+	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/impl/devopt"
+)
+
+func TestGCRemovesDeletedProjects(t *testing.T) {
+	t.Setenv(envir.XDGStateHome, t.TempDir())
+	t.Setenv(envir.XDGDataHome, t.TempDir())
+
+	kept, deleted := t.TempDir(), t.TempDir()
+	for _, dir := range []string{kept, deleted} {
+		if err := os.WriteFile(filepath.Join(dir, devconfig.DefaultName), []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, devboxStateDir, "virtenv"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, devboxStateDir, "virtenv", "data"), []byte("12345"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		recordProject(dir)
+	}
+	if err := os.Remove(filepath.Join(deleted, devconfig.DefaultName)); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := computeDiskUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage.Projects) != 2 || usage.StateSize != 10 {
+		t.Fatalf("got disk usage %+v, want 2 projects using 10 bytes", usage)
+	}
+
+	out := &bytes.Buffer{}
+	if err := GC(context.Background(), out, devopt.GCOpts{DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !fileutil.Exists(filepath.Join(deleted, devboxStateDir)) {
+		t.Fatalf("dry run removed the state of the deleted project:\n%s", out)
+	}
+
+	if err := GC(context.Background(), out, devopt.GCOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if fileutil.Exists(filepath.Join(deleted, devboxStateDir)) {
+		t.Errorf("state of the deleted project wasn't removed:\n%s", out)
+	}
+	if !fileutil.Exists(filepath.Join(kept, devboxStateDir)) {
+		t.Errorf("state of an existing project was removed:\n%s", out)
+	}
+	projects, err := knownProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || projects[0].Dir != kept {
+		t.Errorf("got known projects %+v, want only %s", projects, kept)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for bytes, want := range map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	} {
+		if got := formatSize(bytes); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
+
+func TestRecordProjectConcurrently(t *testing.T) {
+	t.Setenv(envir.XDGStateHome, t.TempDir())
+
+	dirs := []string{}
+	for i := 0; i < 10; i++ {
+		dirs = append(dirs, t.TempDir())
+	}
+	var wg sync.WaitGroup
+	for _, dir := range dirs {
+		wg.Add(1)
+		go func(dir string) {
+			defer wg.Done()
+			recordProject(dir)
+		}(dir)
+	}
+	wg.Wait()
+
+	recent, err := readRecentProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if _, ok := recent[dir]; !ok {
+			t.Errorf("project %s wasn't recorded", dir)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/trace"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/conf"
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/devconfig"
+	"go.jetpack.io/devbox/internal/devpkg"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/plugin"
+)
+
+// Origins of the values in an effective config, other than includes which are
+// identified by their reference (e.g. "path:../base.json").
+const (
+	originProject     = devconfig.DefaultName
+	originPrintDevEnv = "nix print-dev-env"
+	originPluginFmt   = "plugin:%s"
+)
+
+// effectiveConfig is the fully resolved definition of a devbox environment,
+// after includes, plugins and the lockfile are applied.
+type effectiveConfig struct {
+	Packages  []effectivePackage  `json:"packages"`
+	Env       []effectiveEnvVar   `json:"env"`
+	InitHooks []effectiveInitHook `json:"init_hooks"`
+	Scripts   []effectiveScript   `json:"scripts"`
+	Services  []effectiveService  `json:"services"`
+
+	// NixEnv is false when the environment computed by nix isn't available
+	// because the project hasn't been installed yet.
+	NixEnv bool `json:"nix_env"`
+}
+
+type effectivePackage struct {
+	Name     string `json:"name"`
+	Resolved string `json:"resolved,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Origin   string `json:"origin"`
+}
+
+type effectiveEnvVar struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+}
+
+type effectiveInitHook struct {
+	Command string `json:"command"`
+	Origin  string `json:"origin"`
+}
+
+type effectiveScript struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Command     string            `json:"command"`
+	DependsOn   []string          `json:"depends_on,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Cwd         string            `json:"cwd,omitempty"`
+	Watch       []string          `json:"watch,omitempty"`
+	Origin      string            `json:"origin"`
+}
+
+type effectiveService struct {
+	Name               string `json:"name"`
+	ProcessComposePath string `json:"process_compose_path"`
+}
+
+// PrintEffectiveConfig prints the effective config of the project: its
+// packages, env, init hooks, scripts and services after includes, plugins and
+// the lockfile are applied. It doesn't install anything, so the environment
+// computed by nix is only included if the project was previously installed.
+func (d *Devbox) PrintEffectiveConfig(ctx context.Context, asJSON bool) error {
+	ctx, task := trace.NewTask(ctx, "devboxPrintEffectiveConfig")
+	defer task.End()
+
+	cfg, err := d.computeEffectiveConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		data, err := cuecfg.MarshalJSON(cfg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(d.writer, string(data))
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(cfg.print(d.writer))
+}
+
+func (d *Devbox) computeEffectiveConfig(ctx context.Context) (*effectiveConfig, error) {
+	effective := d.EffectiveConfig()
+	result := &effectiveConfig{}
+
+	userPackages := d.PackagesAsInputs()
+	for _, pkg := range userPackages {
+		result.Packages = append(
+			result.Packages,
+			d.effectivePackage(pkg, d.packageOrigin(pkg.Raw)),
+		)
+	}
+	for _, pkg := range userPackages {
+		pluginPackages, err := d.pluginManager.PluginInputs([]*devpkg.Package{pkg})
+		if err != nil {
+			return nil, err
+		}
+		for _, pluginPkg := range pluginPackages {
+			result.Packages = append(
+				result.Packages,
+				d.effectivePackage(pluginPkg, fmt.Sprintf(originPluginFmt, pkg.CanonicalName())),
+			)
+		}
+	}
+
+	env, err := d.effectiveEnv(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+	result.Env = env
+
+	for _, pkg := range userPackages {
+		hooks, err := plugin.InitHooks([]*devpkg.Package{pkg}, d.projectDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, hook := range hooks {
+			result.InitHooks = append(result.InitHooks, effectiveInitHook{
+				Command: hook,
+				Origin:  fmt.Sprintf(originPluginFmt, pkg.CanonicalName()),
+			})
+		}
+	}
+	for _, cfg := range d.configsByInclusionOrder() {
+		if hook := cfg.InitHook(); hook != nil {
+			for _, cmd := range hook.Cmds {
+				result.InitHooks = append(
+					result.InitHooks,
+					effectiveInitHook{Command: cmd, Origin: cfg.Ref},
+				)
+			}
+		}
+	}
+
+	scripts := effective.Scripts()
+	names := maps.Keys(scripts)
+	slices.Sort(names)
+	for _, name := range names {
+		result.Scripts = append(result.Scripts, effectiveScript{
+			Name:        name,
+			Description: scripts[name].Description,
+			Command:     scripts[name].String(),
+			DependsOn:   scripts[name].DependsOn,
+			Env:         scripts[name].Env,
+			Cwd:         scripts[name].Cwd,
+			Watch:       scripts[name].Watch,
+			Origin:      d.scriptOrigin(name),
+		})
+	}
+
+	svcs, err := d.Services()
+	if err != nil {
+		return nil, err
+	}
+	svcNames := maps.Keys(svcs)
+	slices.Sort(svcNames)
+	for _, name := range svcNames {
+		result.Services = append(result.Services, effectiveService{
+			Name:               name,
+			ProcessComposePath: svcs[name].ProcessComposePath,
+		})
+	}
+	return result, nil
+}
+
+// effectiveEnv returns the env variables set by devbox, in the order in which
+// they are applied: nix, plugins and then configs. Variables set by a later
+// source override earlier ones.
+func (d *Devbox) effectiveEnv(
+	ctx context.Context,
+	result *effectiveConfig,
+) ([]effectiveEnvVar, error) {
+	env := map[string]string{}
+	origins := map[string]string{}
+	set := func(vars map[string]string, origin string) {
+		for k, v := range vars {
+			env[k] = v
+			origins[k] = origin
+		}
+	}
+
+	// Only use the cached nix environment to avoid building the project.
+	if fileutil.Exists(d.nixPrintDevEnvCachePath()) {
+		vaf, err := d.nix.PrintDevEnv(ctx, &nix.PrintDevEnvArgs{
+			BinaryCaches:         d.BinaryCaches(),
+			FlakesFilePath:       d.nixFlakesFilePath(),
+			PrintDevEnvCachePath: d.nixPrintDevEnvCachePath(),
+			UsePrintDevEnvCache:  true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		nixEnv := map[string]string{}
+		for key, val := range vaf.Variables {
+			if val.Type == "exported" && !ignoreDevEnvVar[key] {
+				nixEnv[key] = val.Value.(string)
+			}
+		}
+		set(nixEnv, originPrintDevEnv)
+		result.NixEnv = true
+	}
+
+	for _, pkg := range d.PackagesAsInputs() {
+		pluginEnv, err := d.pluginManager.Env([]*devpkg.Package{pkg}, nil, env)
+		if err != nil {
+			return nil, err
+		}
+		set(pluginEnv, fmt.Sprintf(originPluginFmt, pkg.CanonicalName()))
+	}
+	for _, include := range d.cfg.Include {
+		if plugin.IsConfigInclude(include) {
+			continue
+		}
+		pluginEnv, err := d.pluginManager.Env(nil, []string{include}, env)
+		if err != nil {
+			return nil, err
+		}
+		set(pluginEnv, include)
+	}
+
+	for _, cfg := range d.configsByInclusionOrder() {
+		set(conf.OSExpandEnvMap(cfg.Env, env, d.projectDir), cfg.Ref)
+	}
+
+	names := maps.Keys(env)
+	slices.Sort(names)
+	vars := []effectiveEnvVar{}
+	for _, name := range names {
+		vars = append(vars, effectiveEnvVar{
+			Name:   name,
+			Value:  env[name],
+			Origin: origins[name],
+		})
+	}
+	return vars, nil
+}
+
+// configsByInclusionOrder returns the included configs in include order,
+// followed by the project's config, which has "devbox.json" as its Ref.
+func (d *Devbox) configsByInclusionOrder() []*devconfig.IncludedConfig {
+	return append(
+		slices.Clone(d.includedCfgs),
+		&devconfig.IncludedConfig{Config: d.cfg, Ref: originProject},
+	)
+}
+
+// packageOrigin returns where a package of the effective config is declared.
+// Later includes take precedence over earlier ones, matching devconfig.Merge.
+func (d *Devbox) packageOrigin(pkg string) string {
+	cfgs := d.configsByInclusionOrder()
+	for i := len(cfgs) - 1; i >= 0; i-- {
+		if slices.Contains(cfgs[i].Packages, pkg) {
+			return cfgs[i].Ref
+		}
+	}
+	return ""
+}
+
+// scriptOrigin returns where a script of the effective config is declared. The
+// first include that defines a script wins, matching devconfig.Merge.
+func (d *Devbox) scriptOrigin(name string) string {
+	if _, ok := d.cfg.Scripts()[name]; ok {
+		return originProject
+	}
+	for _, cfg := range d.includedCfgs {
+		if _, ok := cfg.Scripts()[name]; ok {
+			return cfg.Ref
+		}
+	}
+	return ""
+}
+
+// effectivePackage returns the package with its resolved reference and version
+// from the lockfile. It doesn't resolve packages that aren't locked yet.
+func (d *Devbox) effectivePackage(pkg *devpkg.Package, origin string) effectivePackage {
+	result := effectivePackage{Name: pkg.Raw, Origin: origin}
+	if locked := d.lockfile.Packages[pkg.Raw]; locked != nil {
+		result.Resolved = locked.Resolved
+		result.Version = locked.Version
+	}
+	return result
+}
+
+func (c *effectiveConfig) print(w io.Writer) error {
+	b := &strings.Builder{}
+
+	fmt.Fprintln(b, "Packages:")
+	for _, pkg := range c.Packages {
+		fmt.Fprintf(b, "  %s (%s)\n", pkg.Name, pkg.Origin)
+		if pkg.Resolved == "" {
+			fmt.Fprintln(b, "    resolved: not locked")
+			continue
+		}
+		fmt.Fprintf(b, "    resolved: %s\n", pkg.Resolved)
+		if pkg.Version != "" {
+			fmt.Fprintf(b, "    version:  %s\n", pkg.Version)
+		}
+	}
+
+	fmt.Fprintln(b, "\nEnv:")
+	if !c.NixEnv {
+		fmt.Fprintln(b, "  (run `devbox install` to include the environment computed by nix)")
+	}
+	for _, v := range c.Env {
+		fmt.Fprintf(b, "  %s=%s (%s)\n", v.Name, v.Value, v.Origin)
+	}
+
+	fmt.Fprintln(b, "\nInit hooks:")
+	for i, hook := range c.InitHooks {
+		fmt.Fprintf(b, "  %d. [%s]\n", i+1, hook.Origin)
+		for _, line := range strings.Split(strings.TrimSpace(hook.Command), "\n") {
+			fmt.Fprintf(b, "     %s\n", line)
+		}
+	}
+
+	fmt.Fprintln(b, "\nScripts:")
+	for _, script := range c.Scripts {
+		fmt.Fprintf(b, "  %s (%s)\n", script.Name, script.Origin)
+		if script.Description != "" {
+			fmt.Fprintf(b, "    description: %s\n", script.Description)
+		}
+		if len(script.DependsOn) > 0 {
+			fmt.Fprintf(b, "    depends on: %s\n", strings.Join(script.DependsOn, ", "))
+		}
+		if script.Cwd != "" {
+			fmt.Fprintf(b, "    cwd: %s\n", script.Cwd)
+		}
+		if len(script.Watch) > 0 {
+			fmt.Fprintf(b, "    watch: %s\n", strings.Join(script.Watch, ", "))
+		}
+		envNames := maps.Keys(script.Env)
+		slices.Sort(envNames)
+		for _, name := range envNames {
+			fmt.Fprintf(b, "    env: %s=%s\n", name, script.Env[name])
+		}
+		for _, line := range strings.Split(strings.TrimSpace(script.Command), "\n") {
+			fmt.Fprintf(b, "    %s\n", line)
+		}
+	}
+
+	fmt.Fprintln(b, "\nServices:")
+	for _, svc := range c.Services {
+		fmt.Fprintf(b, "  %s (%s)\n", svc.Name, svc.ProcessComposePath)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
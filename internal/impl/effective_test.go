@@ -0,0 +1,228 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/impl/devopt"
+)
+
+func TestComputeEffectiveConfig(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  *effectiveConfig
+	}{
+		{
+			name: "project only",
+			files: map[string]string{
+				"devbox.json": `{
+					"packages": ["curl@8"],
+					"env": {"A": "project"},
+					"shell": {
+						"init_hook": "echo project",
+						"scripts": {"test": "echo test"}
+					}
+				}`,
+				"devbox.lock": `{
+					"lockfile_version": "1",
+					"packages": {
+						"curl@8": {"resolved": "github:NixOS/nixpkgs/abc#curl", "version": "8.1.2"}
+					}
+				}`,
+			},
+			want: &effectiveConfig{
+				Packages: []effectivePackage{
+					{Name: "curl@8", Resolved: "github:NixOS/nixpkgs/abc#curl", Version: "8.1.2", Origin: "devbox.json"},
+				},
+				Env: []effectiveEnvVar{{Name: "A", Value: "project", Origin: "devbox.json"}},
+				InitHooks: []effectiveInitHook{
+					{Command: "echo project", Origin: "devbox.json"},
+				},
+				Scripts: []effectiveScript{
+					{Name: "test", Command: "echo test", Origin: "devbox.json"},
+				},
+			},
+		},
+		{
+			name: "include",
+			files: map[string]string{
+				"base.json": `{
+					"packages": ["hello@2.12"],
+					"env": {"A": "base", "B": "base"},
+					"shell": {
+						"init_hook": "echo base",
+						"scripts": {"lint": "echo base lint", "test": "echo base test"}
+					}
+				}`,
+				"devbox.json": `{
+					"include": ["path:base.json"],
+					"packages": ["curl@8"],
+					"env": {"B": "project"},
+					"shell": {
+						"init_hook": "echo project",
+						"scripts": {
+							"test": {
+								"command": "echo project test",
+								"description": "Runs the tests",
+								"depends_on": ["lint"],
+								"cwd": "src"
+							}
+						}
+					}
+				}`,
+			},
+			want: &effectiveConfig{
+				// Packages of the project and of includes aren't locked until
+				// they're installed.
+				Packages: []effectivePackage{
+					{Name: "curl@8", Origin: "devbox.json"},
+					{Name: "hello@2.12", Origin: "path:base.json"},
+				},
+				// The project's env overrides the env of includes.
+				Env: []effectiveEnvVar{
+					{Name: "A", Value: "base", Origin: "path:base.json"},
+					{Name: "B", Value: "project", Origin: "devbox.json"},
+				},
+				// Init hooks of includes run first.
+				InitHooks: []effectiveInitHook{
+					{Command: "echo base", Origin: "path:base.json"},
+					{Command: "echo project", Origin: "devbox.json"},
+				},
+				// The project's scripts override scripts of includes.
+				Scripts: []effectiveScript{
+					{Name: "lint", Command: "echo base lint", Origin: "path:base.json"},
+					{
+						Name:        "test",
+						Description: "Runs the tests",
+						Command:     "echo project test",
+						DependsOn:   []string{"lint"},
+						Cwd:         "src",
+						Origin:      "devbox.json",
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			d, err := Open(&devopt.Opts{Dir: dir, Writer: os.Stderr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.computeEffectiveConfig(context.Background())
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("wrong effective config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintEffectiveConfig(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  *effectiveConfig
+		want string
+	}{
+		{
+			name: "empty",
+			cfg:  &effectiveConfig{},
+			want: `Packages:
+
+Env:
+  (run ` + "`devbox install`" + ` to include the environment computed by nix)
+
+Init hooks:
+
+Scripts:
+
+Services:
+`,
+		},
+		{
+			name: "everything",
+			cfg: &effectiveConfig{
+				Packages: []effectivePackage{
+					{Name: "curl@8", Resolved: "github:NixOS/nixpkgs/abc#curl", Version: "8.1.2", Origin: "devbox.json"},
+					{Name: "hello@2.12", Origin: "path:base.json"},
+				},
+				Env: []effectiveEnvVar{
+					{Name: "PATH", Value: "/nix/store/abc/bin", Origin: originPrintDevEnv},
+				},
+				InitHooks: []effectiveInitHook{
+					{Command: "echo one\necho two\n", Origin: "devbox.json"},
+				},
+				Scripts: []effectiveScript{{
+					Name:        "test",
+					Description: "Runs the tests",
+					Command:     "go test ./...",
+					DependsOn:   []string{"lint", "build"},
+					Env:         map[string]string{"B": "2", "A": "1"},
+					Cwd:         "src",
+					Watch:       []string{"**/*.go"},
+					Origin:      "devbox.json",
+				}},
+				Services: []effectiveService{
+					{Name: "web", ProcessComposePath: "/project/process-compose.yaml"},
+				},
+				NixEnv: true,
+			},
+			want: `Packages:
+  curl@8 (devbox.json)
+    resolved: github:NixOS/nixpkgs/abc#curl
+    version:  8.1.2
+  hello@2.12 (path:base.json)
+    resolved: not locked
+
+Env:
+  PATH=/nix/store/abc/bin (nix print-dev-env)
+
+Init hooks:
+  1. [devbox.json]
+     echo one
+     echo two
+
+Scripts:
+  test (devbox.json)
+    description: Runs the tests
+    depends on: lint, build
+    cwd: src
+    watch: **/*.go
+    env: A=1
+    env: B=2
+    go test ./...
+
+Services:
+  web (/project/process-compose.yaml)
+`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &strings.Builder{}
+			if err := tc.cfg.print(b); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, b.String()); diff != "" {
+				t.Errorf("wrong output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
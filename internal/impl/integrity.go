@@ -0,0 +1,83 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"runtime/trace"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/nix"
+)
+
+// verifyPackageIntegrity compares the NAR hash and size of the store paths of
+// the given packages with the ones in the lockfile, for the user's system.
+// Packages whose store path isn't in the local store are skipped. Hashes that
+// aren't in the lockfile yet are recorded, so that other users of the lockfile
+// can verify that they get the same package.
+func (d *Devbox) verifyPackageIntegrity(ctx context.Context, pkgs []string) error {
+	defer trace.StartRegion(ctx, "verifyPackageIntegrity").End()
+
+	userSystem, err := nix.System()
+	if err != nil {
+		return err
+	}
+	sysInfos := map[string]*lock.SystemInfo{}
+	storePaths := []string{}
+	for _, pkg := range pkgs {
+		locked := d.lockfile.Packages[pkg]
+		if locked == nil || locked.Systems[userSystem] == nil {
+			continue
+		}
+		if sysInfo := locked.Systems[userSystem]; sysInfo.StorePath != "" {
+			sysInfos[pkg] = sysInfo
+			storePaths = append(storePaths, sysInfo.StorePath)
+		}
+	}
+	if len(storePaths) == 0 {
+		return nil
+	}
+	pathInfos, err := nix.StorePathInfos(storePaths...)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range pkgs {
+		sysInfo := sysInfos[pkg]
+		if sysInfo == nil {
+			continue
+		}
+		pathInfo := pathInfos[sysInfo.StorePath]
+		if pathInfo == nil {
+			// The package was installed from another store path, e.g. its
+			// content-addressed path, or isn't installed yet.
+			debug.Log("skipping integrity check of %s: %s is not in the store", pkg, sysInfo.StorePath)
+			continue
+		}
+
+		if sysInfo.NarHash == "" {
+			sysInfo.NarHash = pathInfo.NarHash
+			sysInfo.NarSize = pathInfo.NarSize
+			continue
+		}
+		if sysInfo.NarHash != pathInfo.NarHash ||
+			(sysInfo.NarSize != 0 && sysInfo.NarSize != pathInfo.NarSize) {
+			return usererr.New(
+				"Integrity check failed for %s: %s has NAR hash %s (%d bytes), but "+
+					"devbox.lock expects %s (%d bytes). Run `nix store verify %[2]s` to "+
+					"check the nix store, or `devbox update %[1]s` if devbox.lock is "+
+					"out of date.",
+				pkg,
+				sysInfo.StorePath,
+				pathInfo.NarHash,
+				pathInfo.NarSize,
+				sysInfo.NarHash,
+				sysInfo.NarSize,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/trace"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/devpkg"
+	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/plugin"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+type lockfileVerification struct {
+	Valid    bool           `json:"valid"`
+	Problems []lock.Problem `json:"problems"`
+}
+
+// VerifyLockfile checks that devbox.lock is consistent with devbox.json and
+// with itself, and that the plugins of packages haven't changed version since
+// their files were created. It prints the problems it finds and returns an
+// error if there are any.
+//
+// The Devbox should be opened with devopt.Opts.CachedIncludes, so that
+// devbox.lock is verified as it is on disk: opening a project otherwise
+// fetches and locks the remote includes that aren't locked.
+func (d *Devbox) VerifyLockfile(ctx context.Context, asJSON bool) error {
+	_, task := trace.NewTask(ctx, "devboxVerifyLockfile")
+	defer task.End()
+
+	problems := d.lockfile.Verify()
+	if unknown := d.uncachedLockedIncludes(); len(unknown) > 0 {
+		// The packages of these includes aren't known, so their entries would
+		// be reported as stale.
+		ux.Fwarning(
+			os.Stderr,
+			"Skipping the check for stale entries because the content of %s isn't "+
+				"cached. Run `devbox install` to fetch it.\n",
+			strings.Join(unknown, ", "),
+		)
+		problems = lo.Reject(problems, func(p lock.Problem, _ int) bool {
+			return p.Kind == lock.ProblemStale
+		})
+	}
+	problems = append(problems, d.missingIncludeProblems()...)
+	pluginProblems, err := d.pluginVersionProblems()
+	if err != nil {
+		return err
+	}
+	problems = append(problems, pluginProblems...)
+	result := lockfileVerification{Valid: len(problems) == 0, Problems: problems}
+
+	if asJSON {
+		data, err := cuecfg.MarshalJSON(result)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(d.writer, string(data)); err != nil {
+			return errors.WithStack(err)
+		}
+	} else if result.Valid {
+		ux.Fsuccess(d.writer, "devbox.lock is valid\n")
+	} else {
+		for _, problem := range problems {
+			fmt.Fprintf(d.writer, "%s: %s\n", problem.Kind, problem)
+		}
+	}
+
+	if !result.Valid {
+		return usererr.New(
+			"devbox.lock has %d problem(s). Run `devbox install` to fix missing and "+
+				"stale entries, or `devbox update` to resolve packages again.",
+			len(problems),
+		)
+	}
+	return nil
+}
+
+// missingIncludeProblems returns a problem for each remote include in
+// devbox.json that isn't locked.
+func (d *Devbox) missingIncludeProblems() []lock.Problem {
+	problems := []lock.Problem{}
+	for _, include := range d.cfg.Include {
+		if _, ok := d.lockfile.Packages[include]; ok || !plugin.IsRemoteInclude(include) {
+			continue
+		}
+		problems = append(problems, lock.Problem{
+			Package: include,
+			Kind:    lock.ProblemMissing,
+			Message: "include is in devbox.json but not in devbox.lock",
+		})
+	}
+	return problems
+}
+
+// uncachedLockedIncludes returns the remote includes that are locked, but whose
+// content wasn't loaded because it isn't cached.
+func (d *Devbox) uncachedLockedIncludes() []string {
+	return lo.Filter(d.uncachedIncludes, func(include string, _ int) bool {
+		_, ok := d.lockfile.Packages[include]
+		return ok
+	})
+}
+
+// pluginVersionProblems compares the plugin version in the lockfile, which is
+// the version of the plugin that created the package's files, with the current
+// version of the plugin.
+func (d *Devbox) pluginVersionProblems() ([]lock.Problem, error) {
+	problems := []lock.Problem{}
+	for _, pkg := range devpkg.PackageFromStrings(d.Packages(), d.lockfile) {
+		locked := d.lockfile.Packages[pkg.Raw]
+		if locked == nil || locked.PluginVersion == "" {
+			continue
+		}
+		version, hasPlugin, err := d.pluginManager.PluginVersion(pkg)
+		if err != nil {
+			return nil, err
+		}
+		if !hasPlugin {
+			problems = append(problems, lock.Problem{
+				Package: pkg.Raw,
+				Kind:    lock.ProblemPluginVersion,
+				Message: fmt.Sprintf(
+					"plugin version %s is locked, but the package no longer has a plugin",
+					locked.PluginVersion,
+				),
+			})
+		} else if version != locked.PluginVersion {
+			problems = append(problems, lock.Problem{
+				Package: pkg.Raw,
+				Kind:    lock.ProblemPluginVersion,
+				Message: fmt.Sprintf(
+					"plugin version %s is locked, but the current plugin version is %s",
+					locked.PluginVersion,
+					version,
+				),
+			})
+		}
+	}
+	return problems, nil
+}
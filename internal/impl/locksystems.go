@@ -0,0 +1,54 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"fmt"
+	"runtime/trace"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/searcher"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+// LockSystems resolves the versioned packages of the project for each of the
+// given systems, and saves their store paths and hashes in the lockfile. It
+// returns an error, without saving the lockfile, if some packages have no build
+// for some of the systems.
+func (d *Devbox) LockSystems(ctx context.Context, systems []string) error {
+	_, task := trace.NewTask(ctx, "devboxLockSystems")
+	defer task.End()
+
+	missing := []string{}
+	for _, pkg := range d.Packages() {
+		if _, _, isVersioned := searcher.ParseVersionedPackage(pkg); !isVersioned {
+			ux.Fwarning(d.writer, "Skipping %s, only versioned packages can be locked for other systems\n", pkg)
+			continue
+		}
+		fmt.Fprintf(d.writer, "Locking %s for %s\n", pkg, strings.Join(systems, ", "))
+		err := d.lockfile.ResolveSystems(pkg, systems)
+		var missingErr *lock.MissingSystemsError
+		if errors.As(err, &missingErr) {
+			missing = append(missing, missingErr.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(missing) > 0 {
+		return usererr.New(
+			"devbox.lock was not changed because some packages aren't available on "+
+				"all of the requested systems:\n  %s",
+			strings.Join(missing, "\n  "),
+		)
+	}
+	return d.lockfile.Save()
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/impl/devopt"
+)
+
+func TestLockSystems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// go@1.20.5 has no build for aarch64-linux.
+		_, _ = w.Write([]byte(`{
+  "name": "go",
+  "version": "1.20.5",
+  "systems": {
+    "x86_64-linux": {"commit_hash": "5233fd2ba76a3accb5aaa999c00509a11fd0793c", "store_hash": "0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c", "store_name": "go", "store_version": "1.20.5"},
+    "aarch64-darwin": {"commit_hash": "5233fd2ba76a3accb5aaa999c00509a11fd0793c", "store_hash": "1m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c", "store_name": "go", "store_version": "1.20.5"}
+  }
+}`))
+	}))
+	defer server.Close()
+	t.Setenv(envir.DevboxSearchHost, server.URL)
+	t.Setenv(envir.DevboxSearchIndex, filepath.Join(t.TempDir(), "no-index"))
+	t.Setenv(envir.XDGCacheHome, t.TempDir())
+	t.Setenv("__DEVBOX_NIX_SYSTEM", "x86_64-linux")
+	// Without nix, NAR hashes aren't fetched from the binary cache.
+	t.Setenv("PATH", t.TempDir())
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"devbox.json": `{"packages": ["go@1.20"]}`,
+		"devbox.lock": `{
+  "lockfile_version": "2",
+  "packages": {
+    "go@1.20": {
+      "resolved": "github:NixOS/nixpkgs/5233fd2ba76a3accb5aaa999c00509a11fd0793c#go_1_20",
+      "version": "1.20.5"
+    }
+  }
+}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readLockfile := func() string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, "devbox.lock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	d, err := Open(&devopt.Opts{Dir: dir, Writer: &strings.Builder{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.LockSystems(context.Background(), []string{"aarch64-linux", "aarch64-darwin"})
+	if err == nil || !strings.Contains(err.Error(), "go@1.20 (version 1.20.5) has no build for aarch64-linux") {
+		t.Fatalf("got error %v, want an error about the missing aarch64-linux build", err)
+	}
+	if diff := cmp.Diff(files["devbox.lock"], readLockfile()); diff != "" {
+		t.Errorf("got devbox.lock changed despite the missing system (-want +got):\n%s", diff)
+	}
+
+	d, err = Open(&devopt.Opts{Dir: dir, Writer: &strings.Builder{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.LockSystems(context.Background(), []string{"x86_64-linux", "aarch64-darwin"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	lockfile := readLockfile()
+	for _, storePath := range []string{
+		"/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5",
+		"/nix/store/1m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5",
+	} {
+		if !strings.Contains(lockfile, storePath) {
+			t.Errorf("got devbox.lock:\n%s\nwant it to contain %s", lockfile, storePath)
+		}
+	}
+}
@@ -62,6 +62,9 @@ func (d *Devbox) Add(ctx context.Context, pkgsNames ...string) error {
 		pkgs = append(pkgs, devpkg.PackageFromString(versioned, d.lockfile))
 		d.cfg.Packages = append(d.cfg.Packages, versioned)
 	}
+	if err := d.mergeConfigs(); err != nil {
+		return err
+	}
 
 	// Check packages are valid before adding.
 	for _, pkg := range pkgs {
@@ -119,6 +122,9 @@ func (d *Devbox) Remove(ctx context.Context, pkgs ...string) error {
 			missingPkgs = append(missingPkgs, pkg)
 		}
 	}
+	if err := d.mergeConfigs(); err != nil {
+		return err
+	}
 
 	if len(missingPkgs) > 0 {
 		ux.Fwarning(
@@ -259,6 +265,21 @@ func (d *Devbox) addPackagesToProfile(ctx context.Context, mode installMode) err
 		return nil
 	}
 
+	// Packages with a locked store path are fetched from the binary cache
+	// up front, so that installing them doesn't evaluate nixpkgs.
+	if err := devpkg.SubstituteFromBinaryCache(
+		d.writer,
+		d.BinaryCaches(),
+		devpkg.PackageFromStrings(pkgs, d.lockfile),
+	); err != nil {
+		return err
+	}
+	// Check the substituted store paths against devbox.lock before they're
+	// added to the profile.
+	if err := d.verifyPackageIntegrity(ctx, pkgs); err != nil {
+		return err
+	}
+
 	var msg string
 	if len(pkgs) == 1 {
 		msg = fmt.Sprintf("Installing package: %s.", pkgs[0])
@@ -289,7 +310,9 @@ func (d *Devbox) addPackagesToProfile(ctx context.Context, mode installMode) err
 		}
 	}
 
-	return nil
+	// Packages that were built, instead of substituted above, are only in
+	// the store now. Their hashes are saved with the rest of the lockfile.
+	return d.verifyPackageIntegrity(ctx, pkgs)
 }
 
 func (d *Devbox) removePackagesFromProfile(ctx context.Context, pkgs []string) error {
@@ -421,6 +444,11 @@ outer:
 			if profileInput.Equals(devboxInput) {
 				continue outer
 			}
+			if isStorePath, err := item.IsStorePathOf(devboxInput); err != nil {
+				return nil, err
+			} else if isStorePath {
+				continue outer
+			}
 		}
 		extras = append(extras, item)
 	}
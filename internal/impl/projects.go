@@ -0,0 +1,202 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devconfig"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/services"
+	"go.jetpack.io/devbox/internal/xdg"
+)
+
+// recordProjectInterval is how often the commands that use a project, like
+// shell, run, install and services up, record that it was used. They run often,
+// so the project isn't recorded every time.
+const recordProjectInterval = time.Hour
+
+// recentProjectsLockTimeout is how long to wait for other devbox commands that
+// update the recent projects.
+const recentProjectsLockTimeout = 5 * time.Second
+
+// recentProjects maps the directories of the projects that devbox used to
+// the last time they were used, so that `devbox du` and `devbox gc` can find
+// them.
+type recentProjects map[string]time.Time
+
+func recentProjectsPath() string {
+	return xdg.StateSubpath(filepath.FromSlash("devbox/projects.json"))
+}
+
+func readRecentProjects() (recentProjects, error) {
+	projects := recentProjects{}
+	data, err := os.ReadFile(recentProjectsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return projects, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", recentProjectsPath())
+	}
+	return projects, nil
+}
+
+// writeRecentProjects replaces the recent projects. Callers hold the lock
+// taken by updateRecentProjects.
+func writeRecentProjects(projects recentProjects) error {
+	path := recentProjectsPath()
+	data, err := cuecfg.MarshalJSON(projects)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that concurrent devbox commands
+	// never read a partially written file.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "projects-*.json")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.WithStack(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp.Name(), path))
+}
+
+// updateRecentProjects calls update with the recent projects and writes them
+// if it returns true. It holds a lock while doing so, so that concurrent
+// devbox commands don't drop each other's changes.
+func updateRecentProjects(update func(recentProjects) bool) error {
+	path := recentProjectsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := fileutil.LockFile(lock, recentProjectsLockTimeout); err != nil {
+		return err
+	}
+	// Closing the file releases the lock.
+	defer lock.Close()
+
+	projects, err := readRecentProjects()
+	if err != nil {
+		return err
+	}
+	if !update(projects) {
+		return nil
+	}
+	return writeRecentProjects(projects)
+}
+
+// recordProject records that the project in dir was used. Failing to record it
+// only means that `devbox du` and `devbox gc` don't know about it, so errors
+// are logged and ignored.
+func recordProject(dir string) {
+	// Check without the lock first, since the project is usually recorded
+	// already.
+	projects, err := readRecentProjects()
+	if err != nil {
+		debug.Log("failed to read recent projects: %v", err)
+		return
+	}
+	if lastUsed, ok := projects[dir]; ok && time.Since(lastUsed) < recordProjectInterval {
+		return
+	}
+	err = updateRecentProjects(func(projects recentProjects) bool {
+		projects[dir] = time.Now().UTC().Truncate(time.Second)
+		return true
+	})
+	if err != nil {
+		debug.Log("failed to record project %s: %v", dir, err)
+	}
+}
+
+// knownProject is a project that devbox knows about.
+type knownProject struct {
+	Dir string
+	// Exists is false if the project's directory or devbox.json was deleted.
+	Exists bool
+	Global bool
+	// LastUsed is zero if the project was never recorded by recordProject,
+	// for example because it was only used by older versions of devbox.
+	LastUsed time.Time
+}
+
+// knownProjects returns the projects that devbox knows about: the recently
+// used projects and the global profile, sorted by directory.
+func knownProjects() ([]knownProject, error) {
+	recent, err := readRecentProjects()
+	if err != nil {
+		return nil, err
+	}
+	dirs := maps.Keys(recent)
+
+	globalDir, err := GlobalDataPath()
+	if err != nil {
+		return nil, err
+	}
+	// The global profile only counts if it was used.
+	if fileutil.Exists(filepath.Join(globalDir, devconfig.DefaultName)) {
+		dirs = append(dirs, globalDir)
+	}
+
+	slices.Sort(dirs)
+	dirs = slices.Compact(dirs)
+	projects := make([]knownProject, 0, len(dirs))
+	for _, dir := range dirs {
+		projects = append(projects, knownProject{
+			Dir:      dir,
+			Exists:   fileutil.Exists(filepath.Join(dir, devconfig.DefaultName)),
+			Global:   dir == globalDir,
+			LastUsed: recent[dir],
+		})
+	}
+	return projects, nil
+}
+
+// serviceProjectDirs returns the directories of the projects that devbox knows
+// about, including the projects whose process-compose instance was recorded by
+// an older version of devbox, which didn't record projects.
+func serviceProjectDirs() ([]string, error) {
+	legacyDirs, err := services.ImportLegacyInstances()
+	if err != nil {
+		debug.Log("failed to import the process-compose instances of older versions of devbox: %v", err)
+	}
+	for _, dir := range legacyDirs {
+		recordProject(dir)
+	}
+	return knownProjectDirs()
+}
+
+// knownProjectDirs returns the directories of the projects that devbox knows
+// about.
+func knownProjectDirs() ([]string, error) {
+	projects, err := knownProjects()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, len(projects))
+	for _, project := range projects {
+		dirs = append(dirs, project.Dir)
+	}
+	return dirs, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime/trace"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/samber/lo"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/conf"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/shellgen"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+// scriptPrefixColors are used to tell apart the output of scripts that run in
+// parallel.
+var scriptPrefixColors = []color.Attribute{
+	color.FgCyan,
+	color.FgMagenta,
+	color.FgGreen,
+	color.FgYellow,
+	color.FgBlue,
+	color.FgRed,
+}
+
+// RunScriptsInParallel runs scripts at the same time, after running their
+// dependencies. The output of each script is prefixed with its name. All
+// scripts run to completion even if one of them fails, in which case the
+// returned error has the exit code of the first failed script. If a script
+// can't start, the scripts that already started are killed.
+func (d *Devbox) RunScriptsInParallel(ctx context.Context, names []string) error {
+	ctx, task := trace.NewTask(ctx, "devboxRunParallel")
+	defer task.End()
+
+	names = lo.Uniq(names)
+	scripts := d.EffectiveConfig().Scripts()
+	for _, name := range names {
+		if _, ok := scripts[name]; !ok {
+			return usererr.New("script %s is not defined in devbox.json", name)
+		}
+	}
+
+	env, err := d.prepareRun(ctx)
+	if err != nil {
+		return err
+	}
+
+	deps, err := d.scriptDependencies(names...)
+	if err != nil {
+		return err
+	}
+	if err := d.runScriptsInOrder(deps, env); err != nil {
+		return err
+	}
+	// A script that another script depends on already ran as a dependency.
+	names = lo.Filter(names, func(name string, _ int) bool {
+		return !slices.Contains(deps, name)
+	})
+
+	width := len(lo.MaxBy(names, func(a, b string) bool { return len(a) > len(b) }))
+	out := &lockedWriter{w: os.Stdout}
+	errs := make([]error, len(names))
+	// All the commands are created before any of them starts, so that a
+	// script that can't run doesn't leave the others running.
+	cmds := make([]*exec.Cmd, len(names))
+	writers := make([][]*prefixWriter, len(names))
+	for i, name := range names {
+		prefix := color.New(scriptPrefixColors[i%len(scriptPrefixColors)]).
+			Sprintf("[%-*s] ", width, name)
+		cmd, err := nix.ScriptCommand(
+			d.projectDir,
+			shellgen.ScriptPath(d.projectDir, name),
+			d.scriptEnv(name, env),
+		)
+		if err != nil {
+			return err
+		}
+		stdout := &prefixWriter{w: out, prefix: prefix}
+		stderr := &prefixWriter{w: out, prefix: prefix}
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		cmds[i] = cmd
+		writers[i] = []*prefixWriter{stdout, stderr}
+	}
+
+	var wg sync.WaitGroup
+	for i, cmd := range cmds {
+		debug.Log("Executing: %v", cmd.Args)
+		if err := cmd.Start(); err != nil {
+			// Stop the scripts that already started, instead of leaving them
+			// running after devbox exits.
+			for _, started := range cmds[:i] {
+				if err := started.Process.Kill(); err != nil {
+					debug.Log("failed to kill script: %v", err)
+				}
+			}
+			wg.Wait()
+			return err
+		}
+		wg.Add(1)
+		go func(i int, cmd *exec.Cmd) {
+			defer wg.Done()
+			errs[i] = usererr.NewExecError(cmd.Wait())
+			for _, w := range writers[i] {
+				w.Flush()
+			}
+		}(i, cmd)
+	}
+	wg.Wait()
+
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		var exitErr *usererr.ExitError
+		if errors.As(err, &exitErr) {
+			ux.Ferror(d.writer, "script %s exited with code %d\n", names[i], exitErr.ExitCode())
+		} else {
+			ux.Ferror(d.writer, "script %s failed: %v\n", names[i], err)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// runScriptsInOrder runs scripts one after the other, and stops at the first
+// one that fails.
+func (d *Devbox) runScriptsInOrder(names []string, env map[string]string) error {
+	for _, name := range names {
+		ux.Finfo(d.writer, "Running script %s\n", name)
+		err := nix.RunScript(
+			d.projectDir,
+			shellgen.ScriptPath(d.projectDir, name),
+			d.scriptEnv(name, env),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scriptEnv returns env with the env variables of a script applied. It
+// returns env unchanged if the script doesn't set any variables.
+func (d *Devbox) scriptEnv(name string, env map[string]string) map[string]string {
+	script := d.EffectiveConfig().Scripts()[name]
+	if script == nil || len(script.Env) == 0 {
+		return env
+	}
+	result := maps.Clone(env)
+	maps.Copy(result, conf.OSExpandEnvMap(script.Env, env, d.projectDir))
+	return result
+}
+
+// scriptDependencies returns the scripts that the given scripts depend on,
+// directly or transitively, in the order in which they must run. Each
+// dependency is only listed once. A given script is only listed if another
+// given script depends on it.
+func (d *Devbox) scriptDependencies(names ...string) ([]string, error) {
+	scripts := d.EffectiveConfig().Scripts()
+
+	order := []string{}
+	isDependency := map[string]bool{}
+	done := map[string]bool{}
+	path := []string{} // the scripts being visited, to detect cycles
+	var visit func(name string) error
+	visit = func(name string) error {
+		if done[name] {
+			return nil
+		}
+		if i := slices.Index(path, name); i >= 0 {
+			return usererr.New(
+				"scripts in devbox.json have a dependency cycle: %s",
+				strings.Join(append(path[i:], name), " -> "),
+			)
+		}
+		path = append(path, name)
+		for _, dep := range scripts[name].DependsOn {
+			if _, ok := scripts[dep]; !ok {
+				return usererr.New(
+					"script %s depends on %s, which is not defined in devbox.json",
+					name, dep,
+				)
+			}
+			isDependency[dep] = true
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		done[name] = true
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return lo.Filter(order, func(name string, _ int) bool {
+		return isDependency[name]
+	}), nil
+}
+
+// lockedWriter serializes writes from scripts that run in parallel.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// prefixWriter prefixes every line written to it. It buffers incomplete lines
+// so that lines of different scripts don't get mixed up.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		line, err := p.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the incomplete line until the rest of it is written.
+			p.buf.Reset()
+			p.buf.Write(line)
+			return len(b), nil
+		}
+		if _, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Flush writes the last line if it doesn't end with a newline.
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() > 0 {
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf.Bytes())
+		p.buf.Reset()
+	}
+}
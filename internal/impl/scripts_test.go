@@ -0,0 +1,62 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.jetpack.io/devbox/internal/devconfig"
+)
+
+func TestScriptDependencies(t *testing.T) {
+	cfg, err := devconfig.LoadBytes([]byte(`{
+		"shell": {
+			"scripts": {
+				"gen": "go generate ./...",
+				"build": {"command": "go build ./...", "depends_on": ["gen"]},
+				"lint": {"command": "golangci-lint run", "depends_on": ["gen"]},
+				"test": {"command": "go test ./...", "depends_on": ["build"]},
+				"ci": {"depends_on": ["lint", "test"]},
+				"a": {"command": "a", "depends_on": ["b"]},
+				"b": {"command": "b", "depends_on": ["a"]},
+				"broken": {"command": "x", "depends_on": ["missing"]}
+			}
+		}
+	}`), ".json")
+	require.NoError(t, err)
+	d := &Devbox{cfg: cfg, effectiveCfg: cfg}
+
+	deps, err := d.scriptDependencies("test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gen", "build"}, deps)
+
+	// gen runs once even though both lint and build depend on it.
+	deps, err = d.scriptDependencies("ci")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gen", "lint", "build", "test"}, deps)
+
+	// build is a dependency of test, so it's listed even though it's given.
+	deps, err = d.scriptDependencies("build", "test", "lint")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gen", "build"}, deps)
+
+	_, err = d.scriptDependencies("a")
+	assert.ErrorContains(t, err, "a -> b -> a")
+
+	_, err = d.scriptDependencies("broken")
+	assert.ErrorContains(t, err, "depends on missing")
+}
+
+func TestPrefixWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := &prefixWriter{w: out, prefix: "[test] "}
+	_, _ = w.Write([]byte("one\ntw"))
+	_, _ = w.Write([]byte("o\nthree"))
+	w.Flush()
+	assert.Equal(t, "[test] one\n[test] two\n[test] three\n", out.String())
+}
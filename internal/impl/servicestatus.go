@@ -0,0 +1,183 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/f1bonacc1/process-compose/src/types"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/services"
+)
+
+// serviceStatusStopped is the status of the services when process-compose
+// isn't running.
+const serviceStatusStopped = "Stopped"
+
+// serviceStatuses is the status of the services of a project.
+type serviceStatuses struct {
+	// ProcessComposeRunning is false if process-compose isn't running, in which
+	// case all the services are stopped.
+	ProcessComposeRunning bool            `json:"process_compose_running"`
+	Services              []serviceStatus `json:"services"`
+}
+
+type serviceStatus struct {
+	Name string `json:"name"`
+	// Status is the state of the service in process-compose, like "Running"
+	// or "Completed".
+	Status string `json:"status"`
+	Pid    int    `json:"pid,omitempty"`
+	// Ports are the TCP ports that the service listens on.
+	Ports         []int `json:"ports"`
+	UptimeSeconds int64 `json:"uptime_seconds"`
+	Restarts      int   `json:"restarts"`
+	// Health is "Ready" or "Not Ready" if the service has a readiness probe.
+	Health   string `json:"health,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+func (d *Devbox) computeServiceStatuses(ctx context.Context) (*serviceStatuses, error) {
+	svcSet, err := d.Services()
+	if err != nil {
+		return nil, err
+	}
+	result := &serviceStatuses{Services: []serviceStatus{}}
+
+	if !services.ProcessManagerIsRunning(d.projectDir) {
+		names := maps.Keys(svcSet)
+		slices.Sort(names)
+		for _, name := range names {
+			result.Services = append(result.Services, serviceStatus{
+				Name:   name,
+				Status: serviceStatusStopped,
+				Ports:  []int{},
+			})
+		}
+		return result, nil
+	}
+
+	processes, err := services.ListServices(ctx, d.projectDir, d.writer)
+	if err != nil {
+		return nil, err
+	}
+	result.ProcessComposeRunning = true
+	for _, process := range processes {
+		status := serviceStatus{
+			Name:          process.Name,
+			Status:        process.Status,
+			Pid:           process.Pid,
+			Ports:         []int{},
+			UptimeSeconds: int64(process.Uptime / time.Second),
+			Restarts:      process.Restarts,
+			ExitCode:      process.ExitCode,
+		}
+		if process.Health != types.ProcessHealthUnknown {
+			status.Health = process.Health
+		}
+		if process.Pid > 0 {
+			// The ports are only informative, so the status is still printed
+			// if they can't be detected.
+			if status.Ports, err = services.ListeningPorts(process.Pid); err != nil {
+				debug.Log("failed to detect the ports of service %s: %v", process.Name, err)
+				status.Ports = []int{}
+			}
+		}
+		result.Services = append(result.Services, status)
+	}
+	slices.SortFunc(result.Services, func(a, b serviceStatus) bool {
+		return a.Name < b.Name
+	})
+	return result, nil
+}
+
+func (s *serviceStatuses) print(w io.Writer) error {
+	if len(s.Services) == 0 {
+		_, err := fmt.Fprintln(w, "No services found in your project")
+		return errors.WithStack(err)
+	}
+
+	if !s.ProcessComposeRunning {
+		fmt.Fprintln(w, "No services currently running. Run `devbox services up` to start them:")
+		fmt.Fprintln(w, "")
+		for _, svc := range s.Services {
+			fmt.Fprintf(w, "  %s\n", svc.Name)
+		}
+		return nil
+	}
+
+	fmt.Fprintln(w, "Services running in process-compose:")
+	tw := tabwriter.NewWriter(w, 3, 2, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tPID\tPORTS\tUPTIME\tRESTARTS\tHEALTH\tEXIT CODE")
+	for _, svc := range s.Services {
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%s\t%d\t%s\t%d\n",
+			svc.Name,
+			svc.Status,
+			orDash(svc.Pid > 0, strconv.Itoa(svc.Pid)),
+			orDash(len(svc.Ports) > 0, joinPorts(svc.Ports)),
+			orDash(svc.UptimeSeconds > 0, (time.Duration(svc.UptimeSeconds)*time.Second).String()),
+			svc.Restarts,
+			orDash(svc.Health != "", svc.Health),
+			svc.ExitCode,
+		)
+	}
+	return errors.WithStack(tw.Flush())
+}
+
+// orDash returns value if ok, or "-" to mark a missing value in a table.
+func orDash(ok bool, value string) string {
+	if !ok {
+		return "-"
+	}
+	return value
+}
+
+func joinPorts(ports []int) string {
+	strs := make([]string, 0, len(ports))
+	for _, port := range ports {
+		strs = append(strs, strconv.Itoa(port))
+	}
+	return strings.Join(strs, ",")
+}
+
+// ListServices prints the status of the project's services to w: their state
+// in process-compose, PID, listening ports, uptime, restarts, readiness and
+// exit code. Other messages go to the Devbox's writer, so that w only has the
+// status.
+func (d *Devbox) ListServices(ctx context.Context, w io.Writer, asJSON bool) error {
+	if !d.IsEnvEnabled() {
+		args := []string{"services", "ls"}
+		if asJSON {
+			args = append(args, "--json")
+		}
+		return d.RunScript(ctx, "devbox", args)
+	}
+
+	statuses, err := d.computeServiceStatuses(ctx)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		data, err := cuecfg.MarshalJSON(statuses)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return errors.WithStack(err)
+	}
+	return statuses.print(w)
+}
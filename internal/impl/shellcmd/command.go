@@ -5,6 +5,7 @@ package shellcmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"unicode"
@@ -20,6 +21,11 @@ const (
 
 	// CmdString formats shell commands as a single string.
 	CmdString
+
+	// CmdObject formats shell commands as an object, which allows scripts to
+	// declare extra fields such as their dependencies. The commands are in
+	// the "command" field, formatted according to CmdsMarshalAs.
+	CmdObject
 )
 
 // CmdFormat defines a way of formatting shell commands in a devbox config.
@@ -31,14 +37,16 @@ func (c CmdFormat) String() string {
 		return "array"
 	case CmdString:
 		return "string"
+	case CmdObject:
+		return "object"
 	default:
 		return fmt.Sprintf("invalid (%d)", c)
 	}
 }
 
 // Commands marshals and unmarshals shell commands from a devbox config
-// as either a single string or an array of strings. It preserves the original
-// value such that:
+// as either a single string, an array of strings or an object. It preserves
+// the original value such that:
 //
 //	data == marshal(unmarshal(data)))
 type Commands struct {
@@ -48,6 +56,37 @@ type Commands struct {
 	// formats them as an array.
 	MarshalAs CmdFormat
 	Cmds      []string
+
+	// CmdsMarshalAs determines how the "command" field is encoded when
+	// MarshalAs is CmdObject. It can't be CmdObject itself.
+	CmdsMarshalAs CmdFormat
+
+	// The following fields describe scripts, and can only be set in the
+	// object format.
+
+	// Description is shown when listing scripts.
+	Description string
+	// DependsOn lists the scripts that must run before these commands.
+	DependsOn []string
+	// Env overrides env variables when running these commands. Values can
+	// reference other variables with $VAR or ${VAR}.
+	Env map[string]string
+	// Cwd is the directory, relative to the project, in which the commands
+	// run. They run in the project's directory if it's empty.
+	Cwd string
+	// Watch lists globs, relative to the project, of the files that rerun
+	// these commands when they change in watch mode.
+	Watch []string
+}
+
+// commandsObject is the object format of Commands.
+type commandsObject struct {
+	Description string            `json:"description,omitempty"`
+	Command     *Commands         `json:"command,omitempty"`
+	DependsOn   []string          `json:"depends_on,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Cwd         string            `json:"cwd,omitempty"`
+	Watch       []string          `json:"watch,omitempty"`
 }
 
 // AppendScript appends each line of a script to s.Cmds. It also applies the
@@ -85,14 +124,26 @@ func (s Commands) MarshalJSON() ([]byte, error) {
 		return cuecfg.MarshalJSON(s.Cmds)
 	case CmdString:
 		return cuecfg.MarshalJSON(s.String())
+	case CmdObject:
+		obj := commandsObject{
+			Description: s.Description,
+			DependsOn:   s.DependsOn,
+			Env:         s.Env,
+			Cwd:         s.Cwd,
+			Watch:       s.Watch,
+		}
+		if s.Cmds != nil {
+			obj.Command = &Commands{MarshalAs: s.CmdsMarshalAs, Cmds: s.Cmds}
+		}
+		return cuecfg.MarshalJSON(obj)
 	default:
 		panic(fmt.Sprintf("invalid command format: %s", s.MarshalAs))
 	}
 }
 
 // UnmarshalJSON unmarshals shell commands from a string, an array of strings,
-// or null. When the JSON value is a string, it unmarshals into the first index
-// of s.Cmds.
+// an object or null. When the JSON value is a string, it unmarshals into the
+// first index of s.Cmds.
 func (s *Commands) UnmarshalJSON(data []byte) error {
 	if len(data) == 0 || string(data) == "null" {
 		s.MarshalAs = CmdArray
@@ -109,6 +160,25 @@ func (s *Commands) UnmarshalJSON(data []byte) error {
 	case '[':
 		s.MarshalAs = CmdArray
 		return json.Unmarshal(data, &s.Cmds)
+	case '{':
+		obj := commandsObject{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if obj.Command != nil && obj.Command.MarshalAs == CmdObject {
+			return errors.New("the command of a script can't be an object")
+		}
+		s.MarshalAs = CmdObject
+		s.Description = obj.Description
+		s.DependsOn = obj.DependsOn
+		s.Env = obj.Env
+		s.Cwd = obj.Cwd
+		s.Watch = obj.Watch
+		if obj.Command != nil {
+			s.CmdsMarshalAs = obj.Command.MarshalAs
+			s.Cmds = obj.Command.Cmds
+		}
+		return nil
 	default:
 		return nil
 	}
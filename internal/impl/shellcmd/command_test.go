@@ -99,6 +99,101 @@ func TestCommandsUnmarshalString(t *testing.T) {
 	}
 }
 
+func TestCommandsUnmarshalObject(t *testing.T) {
+	tests := []struct {
+		jsonIn string
+		want   Commands
+	}{
+		{
+			jsonIn: `{
+  "command": "go test ./...",
+  "depends_on": [
+    "build"
+  ]
+}`,
+			want: Commands{
+				MarshalAs:     CmdObject,
+				Cmds:          []string{"go test ./..."},
+				CmdsMarshalAs: CmdString,
+				DependsOn:     []string{"build"},
+			},
+		},
+		{
+			jsonIn: `{
+  "command": [
+    "go vet ./...",
+    "go test ./..."
+  ]
+}`,
+			want: Commands{
+				MarshalAs:     CmdObject,
+				Cmds:          []string{"go vet ./...", "go test ./..."},
+				CmdsMarshalAs: CmdArray,
+			},
+		},
+		{
+			jsonIn: `{
+  "depends_on": [
+    "lint",
+    "test"
+  ]
+}`,
+			want: Commands{
+				MarshalAs: CmdObject,
+				DependsOn: []string{"lint", "test"},
+			},
+		},
+		{
+			jsonIn: `{
+  "description": "Run the web app",
+  "command": "npm start",
+  "env": {
+    "PORT": "8080"
+  },
+  "cwd": "web",
+  "watch": [
+    "web/**/*.js"
+  ]
+}`,
+			want: Commands{
+				MarshalAs:     CmdObject,
+				Cmds:          []string{"npm start"},
+				CmdsMarshalAs: CmdString,
+				Description:   "Run the web app",
+				Env:           map[string]string{"PORT": "8080"},
+				Cwd:           "web",
+				Watch:         []string{"web/**/*.js"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.jsonIn, func(t *testing.T) {
+			got := Commands{}
+			if err := json.Unmarshal([]byte(test.jsonIn), &got); err != nil {
+				t.Fatal("Got error unmarshalling test input:", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Got incorrect commands (-want +got):\n%s", diff)
+			}
+			b, err := cuecfg.MarshalJSON(got)
+			if err != nil {
+				t.Fatal("Got error marshalling back to JSON:", err)
+			}
+			if diff := cmp.Diff(test.jsonIn, string(b)); diff != "" {
+				t.Errorf("Got different JSON after unmarshalling and re-marshalling (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCommandsUnmarshalNestedObject(t *testing.T) {
+	got := Commands{}
+	err := json.Unmarshal([]byte(`{"command": {"command": "echo"}}`), &got)
+	if err == nil {
+		t.Error("Got nil error for nested object, want error.")
+	}
+}
+
 func TestCommandsString(t *testing.T) {
 	tests := []struct {
 		jsonIn string
@@ -7,11 +7,11 @@ import (
 	"context"
 	"fmt"
 
-	"go.jetpack.io/devbox/internal/boxcli/featureflag"
 	"go.jetpack.io/devbox/internal/devpkg"
 	"go.jetpack.io/devbox/internal/lock"
 	"go.jetpack.io/devbox/internal/nix"
 	"go.jetpack.io/devbox/internal/nix/nixprofile"
+	"go.jetpack.io/devbox/internal/plugin"
 	"go.jetpack.io/devbox/internal/searcher"
 	"go.jetpack.io/devbox/internal/shellgen"
 	"go.jetpack.io/devbox/internal/ux"
@@ -44,6 +44,10 @@ func (d *Devbox) Update(ctx context.Context, pkgs ...string) error {
 		}
 	}
 
+	if len(pkgs) == 0 {
+		d.unlockRemoteIncludes()
+	}
+
 	for _, pkg := range pendingPackagesToUpdate {
 		if _, _, isVersioned := searcher.ParseVersionedPackage(pkg.Raw); !isVersioned {
 			if err = d.attemptToUpgradeFlake(pkg); err != nil {
@@ -110,43 +114,62 @@ func (d *Devbox) updateDevboxPackage(
 	}
 
 	// Check if the package's system info is missing, or not complete.
-	if featureflag.RemoveNixpkgs.Enabled() {
-		userSystem, err := nix.System()
-		if err != nil {
-			return err
-		}
-
-		// If the newEntry has a system info for the user's system,
-		// then check if we need to update system info
-		if newEntry.Systems[userSystem] != nil {
-
-			// Check if the system info is missing for the user's system.
-			sysInfo := d.lockfile.Packages[pkg.Raw].Systems[userSystem]
-			if sysInfo == nil {
-				if d.lockfile.Packages[pkg.Raw].Systems == nil {
-					d.lockfile.Packages[pkg.Raw].Systems = map[string]*lock.SystemInfo{}
-				}
-				d.lockfile.Packages[pkg.Raw].Systems[userSystem] = newEntry.Systems[userSystem]
-				ux.Finfo(d.writer, "Updated system information for %s\n", pkg)
-				return nil
-			}
-
-			// Check if the CAStorePath is missing for the user's system.
-			// Since any one user cannot add this field for all systems,
-			// we'll need to progressively add it to a project's lockfile.
-			if sysInfo.CAStorePath == "" {
-				// Update the CAStorePath for the user's system
-				d.lockfile.Packages[pkg.Raw].Systems[userSystem].CAStorePath = newEntry.Systems[userSystem].CAStorePath
-				ux.Finfo(d.writer, "Updated system information for %s\n", pkg)
-				return nil
-			}
+	userSystem, err := nix.System()
+	if err != nil {
+		return err
+	}
+	if sysInfo := backfillSystemInfo(existing, newEntry, userSystem); sysInfo != nil {
+		if existing.Systems == nil {
+			existing.Systems = map[string]*lock.SystemInfo{}
 		}
+		existing.Systems[userSystem] = sysInfo
+		ux.Finfo(d.writer, "Updated system information for %s\n", pkg)
+		return nil
 	}
 
 	ux.Finfo(d.writer, "Already up-to-date %s %s\n", pkg, existing.Version)
 	return nil
 }
 
+// backfillSystemInfo returns the system info of the user's system that Update
+// adds to the locked entry of a package whose version didn't change, or nil if
+// the entry is already complete. The user's system info may be missing, or it
+// may lack the CAStorePath or the NAR hash. Since any one user cannot add these
+// fields for all systems, they're progressively added to a project's lockfile.
+func backfillSystemInfo(existing, newEntry *lock.Package, userSystem string) *lock.SystemInfo {
+	newInfo := newEntry.Systems[userSystem]
+	if newInfo == nil {
+		return nil
+	}
+	sysInfo := existing.Systems[userSystem]
+	if sysInfo == nil {
+		return newInfo
+	}
+	backfilled := *sysInfo
+	if backfilled.CAStorePath == "" {
+		backfilled.CAStorePath = newInfo.CAStorePath
+	}
+	if backfilled.NarHash == "" && backfilled.StorePath == newInfo.StorePath {
+		backfilled.NarHash = newInfo.NarHash
+		backfilled.NarSize = newInfo.NarSize
+	}
+	if backfilled == *sysInfo {
+		return nil
+	}
+	return &backfilled
+}
+
+// unlockRemoteIncludes removes remote includes from the lockfile so that their
+// latest content is fetched and locked again.
+func (d *Devbox) unlockRemoteIncludes() {
+	for _, include := range d.cfg.Include {
+		if _, ok := d.lockfile.Packages[include]; ok && plugin.IsRemoteInclude(include) {
+			ux.Finfo(d.writer, "Updating include %s\n", include)
+			delete(d.lockfile.Packages, include)
+		}
+	}
+}
+
 // attemptToUpgradeFlake attempts to upgrade a flake using `nix profile upgrade`
 // and prints an error if it fails, but does not propagate upgrade errors.
 func (d *Devbox) attemptToUpgradeFlake(pkg *devpkg.Package) error {
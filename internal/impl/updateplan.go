@@ -0,0 +1,239 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/trace"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/plugin"
+	"go.jetpack.io/devbox/internal/searcher"
+)
+
+// Changes that `devbox update` can make to a package.
+const (
+	// updateAdd means that the package isn't locked yet, and will be.
+	updateAdd = "add"
+	// updateUpgrade means that the package resolves to a different version.
+	updateUpgrade = "upgrade"
+	// updateSystems means that the version is unchanged, but the system info
+	// of the current system is added to the lockfile.
+	updateSystems = "systems"
+	// updateNone means that the package is already up-to-date.
+	updateNone = "none"
+	// updateConvert means that a legacy package is converted to @latest.
+	updateConvert = "convert"
+	// updateFlake means that the flake is upgraded with `nix profile upgrade`,
+	// which can't be previewed.
+	updateFlake = "flake"
+)
+
+// updatePlan describes the changes that `devbox update` would make to the
+// lockfile.
+type updatePlan struct {
+	Packages []packageUpdate `json:"packages"`
+	// Includes are the remote includes that would be fetched and locked again.
+	Includes []string `json:"includes,omitempty"`
+}
+
+type packageUpdate struct {
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	// ConvertTo is the versioned package that replaces a legacy package.
+	ConvertTo      string   `json:"convert_to,omitempty"`
+	OldVersion     string   `json:"old_version,omitempty"`
+	NewVersion     string   `json:"new_version,omitempty"`
+	OldCommit      string   `json:"old_commit,omitempty"`
+	NewCommit      string   `json:"new_commit,omitempty"`
+	AddedSystems   []string `json:"added_systems,omitempty"`
+	RemovedSystems []string `json:"removed_systems,omitempty"`
+	// UpdatedSystems are locked systems whose info is completed with their
+	// content-addressed store path or NAR hash.
+	UpdatedSystems []string `json:"updated_systems,omitempty"`
+}
+
+// PrintUpdatePlan prints the changes that Update would make to the lockfile,
+// without changing the lockfile or installing anything.
+func (d *Devbox) PrintUpdatePlan(ctx context.Context, asJSON bool, pkgs ...string) error {
+	_, task := trace.NewTask(ctx, "devboxPrintUpdatePlan")
+	defer task.End()
+
+	plan, err := d.computeUpdatePlan(pkgs...)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		data, err := cuecfg.MarshalJSON(plan)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(d.writer, string(data))
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(plan.print(d.writer))
+}
+
+// computeUpdatePlan mirrors Update, but only resolves packages.
+func (d *Devbox) computeUpdatePlan(pkgs ...string) (*updatePlan, error) {
+	inputs, err := d.inputsToUpdate(pkgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like Update, only complete the system info of the user's system.
+	userSystem, err := nix.System()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &updatePlan{Packages: []packageUpdate{}}
+	for _, pkg := range inputs {
+		if pkg.IsLegacy() {
+			plan.Packages = append(plan.Packages, packageUpdate{
+				Name:      pkg.Raw,
+				Change:    updateConvert,
+				ConvertTo: pkg.LegacyToVersioned(),
+			})
+			continue
+		}
+		if _, _, isVersioned := searcher.ParseVersionedPackage(pkg.Raw); !isVersioned {
+			plan.Packages = append(plan.Packages, packageUpdate{
+				Name:   pkg.Raw,
+				Change: updateFlake,
+			})
+			continue
+		}
+		newEntry, err := d.lockfile.FetchResolvedPackage(pkg.Raw)
+		if err != nil {
+			return nil, err
+		}
+		plan.Packages = append(
+			plan.Packages,
+			diffLockedPackage(pkg.Raw, d.lockfile.Packages[pkg.Raw], newEntry, userSystem),
+		)
+	}
+
+	if len(pkgs) == 0 {
+		for _, include := range d.cfg.Include {
+			if _, ok := d.lockfile.Packages[include]; ok && plugin.IsRemoteInclude(include) {
+				plan.Includes = append(plan.Includes, include)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// diffLockedPackage compares the locked entry of a package, which is nil if the
+// package isn't locked, with the entry that it resolves to now. Like Update,
+// it only replaces the entry if its version changed. Otherwise, it only
+// completes the system info of userSystem, unless userSystem is empty.
+func diffLockedPackage(name string, existing, newEntry *lock.Package, userSystem string) packageUpdate {
+	result := packageUpdate{
+		Name:       name,
+		NewVersion: newEntry.Version,
+		NewCommit:  nix.HashFromNixPkgsURL(newEntry.Resolved),
+	}
+	if existing == nil {
+		result.Change = updateAdd
+		result.AddedSystems = lockedSystems(newEntry)
+		return result
+	}
+
+	result.OldVersion = existing.Version
+	result.OldCommit = nix.HashFromNixPkgsURL(existing.Resolved)
+	if existing.Version == newEntry.Version {
+		result.Change = updateNone
+		result.NewCommit = result.OldCommit
+		if userSystem != "" && backfillSystemInfo(existing, newEntry, userSystem) != nil {
+			result.Change = updateSystems
+			if existing.Systems[userSystem] == nil {
+				result.AddedSystems = []string{userSystem}
+			} else {
+				result.UpdatedSystems = []string{userSystem}
+			}
+		}
+		return result
+	}
+
+	result.Change = updateUpgrade
+	oldSystems := lockedSystems(existing)
+	newSystems := lockedSystems(newEntry)
+	for _, system := range newSystems {
+		if !slices.Contains(oldSystems, system) {
+			result.AddedSystems = append(result.AddedSystems, system)
+		}
+	}
+	for _, system := range oldSystems {
+		if !slices.Contains(newSystems, system) {
+			result.RemovedSystems = append(result.RemovedSystems, system)
+		}
+	}
+	return result
+}
+
+func lockedSystems(pkg *lock.Package) []string {
+	systems := maps.Keys(pkg.Systems)
+	slices.Sort(systems)
+	return systems
+}
+
+func (p *updatePlan) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, pkg := range p.Packages {
+		switch pkg.Change {
+		case updateAdd:
+			fmt.Fprintf(tw, "%s\t(not locked) -> %s\t%s\n", pkg.Name, pkg.NewVersion, shortCommit(pkg.NewCommit))
+		case updateUpgrade:
+			fmt.Fprintf(
+				tw,
+				"%s\t%s -> %s\t%s -> %s\n",
+				pkg.Name,
+				pkg.OldVersion,
+				pkg.NewVersion,
+				shortCommit(pkg.OldCommit),
+				shortCommit(pkg.NewCommit),
+			)
+		case updateSystems:
+			fmt.Fprintf(tw, "%s\t%s\tsystem information is updated\n", pkg.Name, pkg.OldVersion)
+		case updateNone:
+			fmt.Fprintf(tw, "%s\t%s\talready up-to-date\n", pkg.Name, pkg.OldVersion)
+		case updateConvert:
+			fmt.Fprintf(tw, "%s\t-> %s\tlegacy package is converted\n", pkg.Name, pkg.ConvertTo)
+		case updateFlake:
+			fmt.Fprintf(tw, "%s\t\tupgraded with `nix profile upgrade`\n", pkg.Name)
+		}
+		if len(pkg.AddedSystems) > 0 {
+			fmt.Fprintf(tw, "\t+ %s\n", strings.Join(pkg.AddedSystems, ", "))
+		}
+		if len(pkg.RemovedSystems) > 0 {
+			fmt.Fprintf(tw, "\t- %s\n", strings.Join(pkg.RemovedSystems, ", "))
+		}
+		if len(pkg.UpdatedSystems) > 0 {
+			fmt.Fprintf(tw, "\t~ %s\n", strings.Join(pkg.UpdatedSystems, ", "))
+		}
+	}
+	for _, include := range p.Includes {
+		fmt.Fprintf(tw, "%s\t\tfetched again\n", include)
+	}
+	return tw.Flush()
+}
+
+// shortCommit abbreviates a nixpkgs commit hash like git does.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
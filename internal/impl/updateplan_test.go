@@ -0,0 +1,222 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/lock"
+)
+
+func TestDiffLockedPackage(t *testing.T) {
+	const (
+		oldCommit = "5233fd2ba76a3accb5aaa999c00509a11fd0793c"
+		newCommit = "a6f6d8f3a1b3a0d1b6c3e52cf0e4c1dbd5e3e7b2"
+	)
+	locked := &lock.Package{
+		Resolved: "github:NixOS/nixpkgs/" + oldCommit + "#go_1_20",
+		Version:  "1.20.5",
+		Systems: map[string]*lock.SystemInfo{
+			"x86_64-linux":  {},
+			"x86_64-darwin": {},
+		},
+	}
+	sameVersion := &lock.Package{
+		Resolved: "github:NixOS/nixpkgs/" + newCommit + "#go_1_20",
+		Version:  "1.20.5",
+	}
+	sameVersionWithSystems := &lock.Package{
+		Resolved: "github:NixOS/nixpkgs/" + newCommit + "#go_1_20",
+		Version:  "1.20.5",
+		Systems: map[string]*lock.SystemInfo{
+			"x86_64-linux":   {StorePath: "/nix/store/a-go-1.20.5", CAStorePath: "/nix/store/b-go-1.20.5"},
+			"aarch64-darwin": {StorePath: "/nix/store/c-go-1.20.5", CAStorePath: "/nix/store/d-go-1.20.5"},
+		},
+	}
+	newVersion := &lock.Package{
+		Resolved: "github:NixOS/nixpkgs/" + newCommit + "#go_1_20",
+		Version:  "1.20.7",
+		Systems: map[string]*lock.SystemInfo{
+			"x86_64-linux":   {},
+			"aarch64-darwin": {},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		existing   *lock.Package
+		newEntry   *lock.Package
+		userSystem string
+		want       packageUpdate
+	}{
+		{
+			name:     "not locked",
+			newEntry: newVersion,
+			want: packageUpdate{
+				Name:         "go@1.20",
+				Change:       updateAdd,
+				NewVersion:   "1.20.7",
+				NewCommit:    newCommit,
+				AddedSystems: []string{"aarch64-darwin", "x86_64-linux"},
+			},
+		},
+		{
+			name:     "same version",
+			existing: locked,
+			newEntry: sameVersion,
+			want: packageUpdate{
+				Name:       "go@1.20",
+				Change:     updateNone,
+				OldVersion: "1.20.5",
+				NewVersion: "1.20.5",
+				OldCommit:  oldCommit,
+				NewCommit:  oldCommit,
+			},
+		},
+		{
+			name:       "same version, missing system",
+			existing:   locked,
+			newEntry:   sameVersionWithSystems,
+			userSystem: "aarch64-darwin",
+			want: packageUpdate{
+				Name:         "go@1.20",
+				Change:       updateSystems,
+				OldVersion:   "1.20.5",
+				NewVersion:   "1.20.5",
+				OldCommit:    oldCommit,
+				NewCommit:    oldCommit,
+				AddedSystems: []string{"aarch64-darwin"},
+			},
+		},
+		{
+			name:       "same version, missing CA store path",
+			existing:   locked,
+			newEntry:   sameVersionWithSystems,
+			userSystem: "x86_64-linux",
+			want: packageUpdate{
+				Name:           "go@1.20",
+				Change:         updateSystems,
+				OldVersion:     "1.20.5",
+				NewVersion:     "1.20.5",
+				OldCommit:      oldCommit,
+				NewCommit:      oldCommit,
+				UpdatedSystems: []string{"x86_64-linux"},
+			},
+		},
+		{
+			name: "same version, missing NAR hash",
+			existing: &lock.Package{
+				Resolved: locked.Resolved,
+				Version:  "1.20.5",
+				Systems: map[string]*lock.SystemInfo{
+					"x86_64-linux": {StorePath: "/nix/store/a-go-1.20.5", CAStorePath: "/nix/store/b-go-1.20.5"},
+				},
+			},
+			newEntry: &lock.Package{
+				Resolved: "github:NixOS/nixpkgs/" + newCommit + "#go_1_20",
+				Version:  "1.20.5",
+				Systems: map[string]*lock.SystemInfo{
+					"x86_64-linux": {
+						StorePath:   "/nix/store/a-go-1.20.5",
+						CAStorePath: "/nix/store/b-go-1.20.5",
+						NarHash:     "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+						NarSize:     120,
+					},
+				},
+			},
+			userSystem: "x86_64-linux",
+			want: packageUpdate{
+				Name:           "go@1.20",
+				Change:         updateSystems,
+				OldVersion:     "1.20.5",
+				NewVersion:     "1.20.5",
+				OldCommit:      oldCommit,
+				NewCommit:      oldCommit,
+				UpdatedSystems: []string{"x86_64-linux"},
+			},
+		},
+		{
+			name:       "same version, complete system",
+			existing:   sameVersionWithSystems,
+			newEntry:   sameVersionWithSystems,
+			userSystem: "x86_64-linux",
+			want: packageUpdate{
+				Name:       "go@1.20",
+				Change:     updateNone,
+				OldVersion: "1.20.5",
+				NewVersion: "1.20.5",
+				OldCommit:  newCommit,
+				NewCommit:  newCommit,
+			},
+		},
+		{
+			name:     "new version",
+			existing: locked,
+			newEntry: newVersion,
+			want: packageUpdate{
+				Name:           "go@1.20",
+				Change:         updateUpgrade,
+				OldVersion:     "1.20.5",
+				NewVersion:     "1.20.7",
+				OldCommit:      oldCommit,
+				NewCommit:      newCommit,
+				AddedSystems:   []string{"aarch64-darwin"},
+				RemovedSystems: []string{"x86_64-darwin"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := diffLockedPackage("go@1.20", test.existing, test.newEntry, test.userSystem)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("wrong update (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdatePlanPrint(t *testing.T) {
+	plan := &updatePlan{
+		Packages: []packageUpdate{
+			{
+				Name:           "go@1.20",
+				Change:         updateUpgrade,
+				OldVersion:     "1.20.5",
+				NewVersion:     "1.20.7",
+				OldCommit:      "5233fd2ba76a3accb5aaa999c00509a11fd0793c",
+				NewCommit:      "a6f6d8f3a1b3a0d1b6c3e52cf0e4c1dbd5e3e7b2",
+				AddedSystems:   []string{"aarch64-darwin"},
+				RemovedSystems: []string{"x86_64-darwin"},
+			},
+			{Name: "jq@latest", Change: updateNone, OldVersion: "1.6"},
+			{Name: "hello", Change: updateConvert, ConvertTo: "hello@latest"},
+			{
+				Name:           "curl@8",
+				Change:         updateSystems,
+				OldVersion:     "8.1.2",
+				UpdatedSystems: []string{"x86_64-linux"},
+			},
+		},
+		Includes: []string{"github:org/repo"},
+	}
+	buf := &bytes.Buffer{}
+	if err := plan.print(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `go@1.20          1.20.5 -> 1.20.7  5233fd2 -> a6f6d8f
+                 + aarch64-darwin
+                 - x86_64-darwin
+jq@latest        1.6              already up-to-date
+hello            -> hello@latest  legacy package is converted
+curl@8           8.1.2            system information is updated
+                 ~ x86_64-linux
+github:org/repo    fetched again
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("wrong output (-want +got):\n%s", diff)
+	}
+}
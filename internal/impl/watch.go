@@ -0,0 +1,188 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/ux"
+	"go.jetpack.io/devbox/internal/watch"
+)
+
+// watchStopTimeout is how long a watched script has to exit after SIGTERM
+// before it is killed.
+const watchStopTimeout = 5 * time.Second
+
+// WatchScript runs a script or command, and runs it again whenever a watched
+// file changes, until it is interrupted. The watched files match globs and, for
+// scripts, the globs in the script's watch field. Files ignored by the
+// project's .gitignore are never watched.
+//
+// Before each run, the previous run is stopped along with every process it
+// started, and the script's dependencies run again.
+func (d *Devbox) WatchScript(
+	ctx context.Context,
+	cmdName string,
+	cmdArgs []string,
+	globs []string,
+) error {
+	ctx, task := trace.NewTask(ctx, "devboxRunWatch")
+	defer task.End()
+
+	for _, glob := range globs {
+		if !doublestar.ValidatePattern(glob) {
+			return usererr.New("invalid glob passed to --watch: %s", glob)
+		}
+	}
+	var deps []string
+	if script, ok := d.EffectiveConfig().Scripts()[cmdName]; ok {
+		globs = append(slices.Clone(globs), script.Watch...)
+		var err error
+		if deps, err = d.scriptDependencies(cmdName); err != nil {
+			return err
+		}
+	}
+	globs = lo.Uniq(globs)
+	if len(globs) == 0 {
+		return usererr.New(
+			"no files to watch for %s. Pass globs with --watch, or set the watch "+
+				"field of the script in devbox.json", cmdName)
+	}
+
+	env, err := d.prepareRun(ctx)
+	if err != nil {
+		return err
+	}
+	// wrap the arg in double-quotes, and escape any double-quotes inside it
+	for idx, arg := range cmdArgs {
+		cmdArgs[idx] = strconv.Quote(arg)
+	}
+	cmdWithArgs, cmdEnv, err := d.commandLine(cmdName, cmdArgs, env)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := watch.New(&watch.Opts{Dir: d.projectDir, Globs: globs})
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var proc *watchedProcess
+	run := func(changed []string) {
+		proc.stop()
+		proc = nil
+		if len(changed) == 1 {
+			ux.Finfo(d.writer, "%s changed, running %s again\n", changed[0], cmdName)
+		} else if len(changed) > 1 {
+			ux.Finfo(d.writer, "%d files changed, running %s again\n", len(changed), cmdName)
+		}
+
+		if err := d.runScriptsInOrder(deps, env); err != nil {
+			ux.Ferror(d.writer, "%v. Waiting for changes...\n", err)
+			return
+		}
+		cmd, err := nix.ScriptCommand(d.projectDir, cmdWithArgs, cmdEnv)
+		if err != nil {
+			ux.Ferror(d.writer, "%v. Waiting for changes...\n", err)
+			return
+		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if proc, err = startWatchedProcess(cmd, cmdName, d.writer); err != nil {
+			ux.Ferror(d.writer, "failed to run %s: %v. Waiting for changes...\n", cmdName, err)
+		}
+	}
+
+	ux.Finfo(d.writer, "Watching %s. Press Ctrl-C to stop.\n", strings.Join(globs, ", "))
+	run(nil)
+	err = watcher.Run(ctx, run)
+	proc.stop()
+	return err
+}
+
+// watchedProcess is one run of a watched script. It runs in its own process
+// group, so that stopping it also stops the processes that it started.
+type watchedProcess struct {
+	cmd     *exec.Cmd
+	done    chan struct{}
+	stopped atomic.Bool
+}
+
+// startWatchedProcess starts cmd, and reports when it exits unless it was
+// stopped. The process doesn't read from stdin, because only the foreground
+// process group of the terminal can.
+func startWatchedProcess(cmd *exec.Cmd, name string, w io.Writer) (*watchedProcess, error) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	debug.Log("Executing: %v", cmd.Args)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &watchedProcess{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		err := usererr.NewExecError(cmd.Wait())
+		if p.stopped.Load() {
+			return
+		}
+		var exitErr *usererr.ExitError
+		if errors.As(err, &exitErr) {
+			ux.Ferror(w, "%s exited with code %d. Waiting for changes...\n", name, exitErr.ExitCode())
+		} else if err != nil {
+			ux.Ferror(w, "%s failed: %v. Waiting for changes...\n", name, err)
+		} else {
+			ux.Finfo(w, "%s finished. Waiting for changes...\n", name)
+		}
+	}()
+	return p, nil
+}
+
+// stop terminates the process group of the process, and waits for the process
+// to exit. The group is killed if the process doesn't exit in time. It does
+// nothing if the process already exited.
+func (p *watchedProcess) stop() {
+	if p == nil {
+		return
+	}
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	p.stopped.Store(true)
+	pgid := p.cmd.Process.Pid
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+		debug.Log("failed to terminate process group %d: %v", pgid, err)
+	}
+	select {
+	case <-p.done:
+	case <-time.After(watchStopTimeout):
+	}
+	// Also kill the processes of the group that outlive its leader.
+	_ = syscall.Kill(-pgid, syscall.SIGKILL)
+	<-p.done
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package impl
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchedProcessStopKillsGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// The background sleep outlives the shell unless the whole group is
+	// stopped.
+	cmd := exec.Command("sh", "-c", "sleep 60 & echo $! > "+pidFile+"; wait")
+	proc, err := startWatchedProcess(cmd, "test", io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var childPID int
+	for start := time.Now(); childPID == 0; {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("timed out waiting for the background process to start")
+		}
+		data, _ := os.ReadFile(pidFile)
+		childPID, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	proc.stop()
+	select {
+	case <-proc.done:
+	default:
+		t.Fatal("process is still running after stop")
+	}
+	for start := time.Now(); processIsRunning(childPID); {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("background process is still running after stop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Stopping a process that already exited does nothing.
+	proc.stop()
+	(*watchedProcess)(nil).stop()
+}
+
+// processIsRunning returns false if the process doesn't exist or is a zombie
+// that is waiting to be reaped. It relies on /proc, so it always returns false
+// on systems without it.
+func processIsRunning(pid int) bool {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	_, state, _ := strings.Cut(string(data), ") ")
+	return !strings.HasPrefix(state, "Z")
+}
@@ -3,14 +3,19 @@
 
 package lock
 
+import "go.jetpack.io/devbox/internal/nix"
+
 type devboxProject interface {
+	BinaryCaches() nix.BinaryCaches
 	ConfigHash() (string, error)
+	IncludeRefs() []string
 	NixPkgsCommitHash() string
 	Packages() []string
 	ProjectDir() string
 }
 
 type Locker interface {
+	BinaryCaches() nix.BinaryCaches
 	LegacyNixpkgsPath(string) string
 	ProjectDir() string
 	Resolve(string) (*Package, error)
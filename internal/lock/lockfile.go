@@ -11,12 +11,16 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/nix"
 	"go.jetpack.io/devbox/internal/searcher"
 
 	"go.jetpack.io/devbox/internal/cuecfg"
 )
 
-const lockFileVersion = "1"
+// lockFileVersion 2 added the NAR hash and size of each system's store path.
+// Version 1 lockfiles are migrated when they are read.
+const lockFileVersion = "2"
 
 // Lightly inspired by package-lock.json
 type File struct {
@@ -29,6 +33,12 @@ type File struct {
 }
 
 type Package struct {
+	// Constraint is the version range of a package like go@^1.20, which the
+	// package was resolved within. Version is the version it resolved to.
+	Constraint string `json:"constraint,omitempty"`
+	// ContentHash is the sha256 of a remote include's content. It pins the
+	// include so that upstream changes are only picked up by `devbox update`.
+	ContentHash   string `json:"content_hash,omitempty"`
 	LastModified  string `json:"last_modified,omitempty"`
 	PluginVersion string `json:"plugin_version,omitempty"`
 	Resolved      string `json:"resolved,omitempty"`
@@ -46,6 +56,22 @@ type SystemInfo struct {
 	// CAStorePath is the content-addressed path for the nix package in /nix/store
 	// It is of the form <hash>-<name>-<version>
 	CAStorePath string `json:"ca_store_path,omitempty"`
+	// NarHash is the SRI hash (sha256-<base64>) of the NAR serialization of
+	// StorePath, and NarSize is its size in bytes. They are recorded when the
+	// package is first installed on the system, and verified when it is
+	// installed again.
+	NarHash string `json:"nar_hash,omitempty"`
+	NarSize int64  `json:"nar_size,omitempty"`
+}
+
+// BinaryCaches returns the binary caches of the lockfile's project. Lockfiles
+// that were read without a project, like the ones that `devbox lock merge`
+// merges, only use the default binary cache.
+func (l *File) BinaryCaches() nix.BinaryCaches {
+	if l.devboxProject == nil {
+		return nix.BinaryCaches{}
+	}
+	return l.devboxProject.BinaryCaches()
 }
 
 func GetFile(project devboxProject) (*File, error) {
@@ -62,9 +88,32 @@ func GetFile(project devboxProject) (*File, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := lockFile.migrate(); err != nil {
+		return nil, err
+	}
 	return lockFile, nil
 }
 
+// migrate upgrades the lockfile to the current version. The upgraded lockfile
+// is written the next time it is saved.
+func (l *File) migrate() error {
+	switch l.LockFileVersion {
+	case lockFileVersion:
+		return nil
+	case "", "1":
+		// Version 2 only adds fields, which are filled in as packages are
+		// installed.
+		l.LockFileVersion = lockFileVersion
+		return nil
+	default:
+		return usererr.New(
+			"devbox.lock has version %s, which this version of devbox doesn't "+
+				"support. Please update devbox.",
+			l.LockFileVersion,
+		)
+	}
+}
+
 func (l *File) Add(pkgs ...string) error {
 	for _, p := range pkgs {
 		if _, err := l.Resolve(p); err != nil {
@@ -146,10 +195,14 @@ func IsLegacyPackage(pkg string) bool {
 		!strings.HasPrefix(pkg, "/")
 }
 
-// Tidy ensures that the lockfile has the set of packages corresponding to the devbox.json config.
-// It gets rid of older packages that are no longer needed.
+// Tidy ensures that the lockfile has the set of packages and includes
+// corresponding to the devbox.json config. It gets rid of older entries that
+// are no longer needed.
 func (l *File) Tidy() {
-	l.Packages = lo.PickByKeys(l.Packages, l.devboxProject.Packages())
+	l.Packages = lo.PickByKeys(
+		l.Packages,
+		append(l.devboxProject.Packages(), l.devboxProject.IncludeRefs()...),
+	)
 }
 
 func lockFilePath(project devboxProject) string {
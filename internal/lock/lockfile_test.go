@@ -0,0 +1,55 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testProjectDir struct {
+	testProject
+	dir string
+}
+
+func (p *testProjectDir) ProjectDir() string { return p.dir }
+
+func TestGetFileMigratesVersion1(t *testing.T) {
+	dir := t.TempDir()
+	v1 := `{
+  "lockfile_version": "1",
+  "packages": {
+    "go@1.20": {
+      "resolved": "github:NixOS/nixpkgs/5233fd2ba76a3accb5aaa999c00509a11fd0793c#go",
+      "version": "1.20.5"
+    }
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "devbox.lock"), []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lockfile, err := GetFile(&testProjectDir{dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lockfile.LockFileVersion != "2" {
+		t.Errorf("got lockfile version %s, want 2", lockfile.LockFileVersion)
+	}
+	if lockfile.Packages["go@1.20"].Version != "1.20.5" {
+		t.Errorf("got packages %v, want go@1.20 to be kept", lockfile.Packages)
+	}
+}
+
+func TestGetFileUnsupportedVersion(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(`{"lockfile_version": "3", "packages": {}}`)
+	if err := os.WriteFile(filepath.Join(dir, "devbox.lock"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetFile(&testProjectDir{dir: dir}); err == nil {
+		t.Error("got no error for lockfile version 3")
+	}
+}
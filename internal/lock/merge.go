@@ -0,0 +1,192 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/searcher"
+)
+
+// MergeResult is the result of a three-way merge of lockfiles.
+type MergeResult struct {
+	File *File
+	// Resolved are the packages that both sides changed differently, and that
+	// were resolved again to settle the conflict.
+	Resolved []string
+	// Conflicts are the packages that both sides changed differently and that
+	// couldn't be resolved again, because a side removed them or because they
+	// aren't versioned packages. File has our side of them.
+	Conflicts []string
+
+	ours, theirs *File
+}
+
+// ReadMergeFile reads a lockfile that git passes to a merge driver. An empty
+// file, which git passes as the base when both sides added devbox.lock, is an
+// empty lockfile.
+func ReadMergeFile(path string) (*File, error) {
+	file := &File{LockFileVersion: lockFileVersion, Packages: map[string]*Package{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return file, nil
+	}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse lockfile %s", path)
+	}
+	if file.Packages == nil {
+		file.Packages = map[string]*Package{}
+	}
+	if err := file.migrate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Merge merges the packages that ours and theirs changed since base. Entries
+// that only one side changed are taken from that side. When both sides changed
+// a versioned package differently, it is resolved again with resolve, which is
+// usually FetchResolvedPackage. Otherwise the package is a conflict.
+func Merge(base, ours, theirs *File, resolve func(pkg string) (*Package, error)) *MergeResult {
+	result := &MergeResult{
+		File: &File{
+			LockFileVersion: ours.LockFileVersion,
+			Packages:        map[string]*Package{},
+		},
+		ours:   ours,
+		theirs: theirs,
+	}
+
+	keys := map[string]bool{}
+	for _, file := range []*File{base, ours, theirs} {
+		for key := range file.Packages {
+			keys[key] = true
+		}
+	}
+	sortedKeys := maps.Keys(keys)
+	slices.Sort(sortedKeys)
+
+	for _, key := range sortedKeys {
+		baseEntry, oursEntry, theirsEntry := base.Packages[key], ours.Packages[key], theirs.Packages[key]
+		var merged *Package
+		switch {
+		case reflect.DeepEqual(oursEntry, theirsEntry):
+			merged = oursEntry
+		case reflect.DeepEqual(baseEntry, oursEntry):
+			merged = theirsEntry
+		case reflect.DeepEqual(baseEntry, theirsEntry):
+			merged = oursEntry
+		default:
+			merged = result.resolveConflict(key, oursEntry, theirsEntry, resolve)
+		}
+		if merged != nil {
+			result.File.Packages[key] = merged
+		}
+	}
+	return result
+}
+
+func (r *MergeResult) resolveConflict(
+	key string,
+	oursEntry, theirsEntry *Package,
+	resolve func(pkg string) (*Package, error),
+) *Package {
+	_, _, isVersioned := searcher.ParseVersionedPackage(key)
+	if oursEntry != nil && theirsEntry != nil && isVersioned && resolve != nil {
+		if resolved, err := resolve(key); err == nil {
+			r.Resolved = append(r.Resolved, key)
+			return resolved
+		}
+	}
+	r.Conflicts = append(r.Conflicts, key)
+	if oursEntry != nil {
+		return oursEntry
+	}
+	return theirsEntry
+}
+
+// Marshal returns the merged lockfile. Each conflict is surrounded by git
+// conflict markers, with our entry and their entry, so the result is only
+// valid JSON if there are no conflicts.
+func (r *MergeResult) Marshal() ([]byte, error) {
+	data, err := cuecfg.MarshalJSON(r.File)
+	if err != nil {
+		return nil, err
+	}
+	out := string(data) + "\n"
+	for _, key := range r.Conflicts {
+		merged, err := marshalEntry(key, r.File.Packages[key])
+		if err != nil {
+			return nil, err
+		}
+		ours, err := marshalEntry(key, r.ours.Packages[key])
+		if err != nil {
+			return nil, err
+		}
+		theirs, err := marshalEntry(key, r.theirs.Packages[key])
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep the comma that follows the entry on both sides, so that the
+		// file is valid JSON after either side is picked.
+		comma := ""
+		idx := strings.Index(out, merged)
+		if idx < 0 {
+			return nil, errors.Errorf("failed to find entry %s in merged lockfile", key)
+		}
+		end := idx + len(merged)
+		if strings.HasPrefix(out[end:], ",") {
+			comma = ","
+			end++
+		}
+		markers := "<<<<<<< ours\n" +
+			sideOfConflict(ours, comma) +
+			"=======\n" +
+			sideOfConflict(theirs, comma) +
+			">>>>>>> theirs"
+		out = out[:idx] + markers + out[end:]
+	}
+	return []byte(out), nil
+}
+
+// marshalEntry returns the "key": {...} line of an entry, indented like in the
+// packages of a lockfile. It returns "" for a nil entry.
+func marshalEntry(key string, entry *Package) (string, error) {
+	if entry == nil {
+		return "", nil
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("    ", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(key); err != nil {
+		return "", errors.WithStack(err)
+	}
+	encodedKey := strings.TrimSpace(buf.String())
+	buf.Reset()
+	if err := enc.Encode(entry); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return "    " + encodedKey + ": " + strings.TrimSpace(buf.String()), nil
+}
+
+func sideOfConflict(entry, comma string) string {
+	if entry == "" {
+		return ""
+	}
+	return entry + comma + "\n"
+}
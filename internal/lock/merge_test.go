@@ -0,0 +1,217 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func lockfileWith(packages map[string]*Package) *File {
+	return &File{LockFileVersion: lockFileVersion, Packages: packages}
+}
+
+func TestMerge(t *testing.T) {
+	goOld := &Package{Resolved: "github:NixOS/nixpkgs/a#go", Version: "1.20.5"}
+	goOurs := &Package{Resolved: "github:NixOS/nixpkgs/b#go", Version: "1.20.6"}
+	goTheirs := &Package{Resolved: "github:NixOS/nixpkgs/c#go", Version: "1.20.7"}
+	goResolved := &Package{Resolved: "github:NixOS/nixpkgs/d#go", Version: "1.20.8"}
+	jq := &Package{Resolved: "github:NixOS/nixpkgs/a#jq", Version: "1.6"}
+	ripgrep := &Package{Resolved: "github:NixOS/nixpkgs/a#ripgrep", Version: "13.0.0"}
+
+	resolve := func(pkg string) (*Package, error) {
+		if pkg == "go@1.20" {
+			return goResolved, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	tests := []struct {
+		name          string
+		base          map[string]*Package
+		ours          map[string]*Package
+		theirs        map[string]*Package
+		want          map[string]*Package
+		wantResolved  []string
+		wantConflicts []string
+	}{
+		{
+			name:   "both sides add packages",
+			base:   map[string]*Package{"go@1.20": goOld},
+			ours:   map[string]*Package{"go@1.20": goOld, "jq@latest": jq},
+			theirs: map[string]*Package{"go@1.20": goOld, "ripgrep@latest": ripgrep},
+			want: map[string]*Package{
+				"go@1.20":        goOld,
+				"jq@latest":      jq,
+				"ripgrep@latest": ripgrep,
+			},
+		},
+		{
+			name:   "one side updates, the other removes another package",
+			base:   map[string]*Package{"go@1.20": goOld, "jq@latest": jq},
+			ours:   map[string]*Package{"go@1.20": goOurs, "jq@latest": jq},
+			theirs: map[string]*Package{"go@1.20": goOld},
+			want:   map[string]*Package{"go@1.20": goOurs},
+		},
+		{
+			name:         "both sides update the same package",
+			base:         map[string]*Package{"go@1.20": goOld},
+			ours:         map[string]*Package{"go@1.20": goOurs},
+			theirs:       map[string]*Package{"go@1.20": goTheirs},
+			want:         map[string]*Package{"go@1.20": goResolved},
+			wantResolved: []string{"go@1.20"},
+		},
+		{
+			name:          "one side updates a package that the other removes",
+			base:          map[string]*Package{"go@1.20": goOld},
+			ours:          map[string]*Package{},
+			theirs:        map[string]*Package{"go@1.20": goTheirs},
+			want:          map[string]*Package{"go@1.20": goTheirs},
+			wantConflicts: []string{"go@1.20"},
+		},
+		{
+			name:          "both sides change a package that can't be resolved",
+			base:          map[string]*Package{"jq@latest": jq},
+			ours:          map[string]*Package{"jq@latest": ripgrep},
+			theirs:        map[string]*Package{"jq@latest": goTheirs},
+			want:          map[string]*Package{"jq@latest": ripgrep},
+			wantConflicts: []string{"jq@latest"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Merge(
+				lockfileWith(test.base),
+				lockfileWith(test.ours),
+				lockfileWith(test.theirs),
+				resolve,
+			)
+			if diff := cmp.Diff(test.want, result.File.Packages); diff != "" {
+				t.Errorf("wrong packages (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantResolved, result.Resolved, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("wrong resolved packages (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantConflicts, result.Conflicts, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("wrong conflicts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeMarshal(t *testing.T) {
+	base := lockfileWith(map[string]*Package{
+		"go@1.20":   {Version: "1.20.5"},
+		"jq@latest": {Version: "1.6"},
+	})
+	ours := lockfileWith(map[string]*Package{
+		"go@1.20":   {Version: "1.20.6"},
+		"jq@latest": {Version: "1.6"},
+	})
+	theirs := lockfileWith(map[string]*Package{
+		"go@1.20":   {Version: "1.20.7"},
+		"jq@latest": {Version: "1.7"},
+	})
+	data, err := Merge(base, ours, theirs, nil).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "lockfile_version": "2",
+  "packages": {
+<<<<<<< ours
+    "go@1.20": {
+      "version": "1.20.6"
+    },
+=======
+    "go@1.20": {
+      "version": "1.20.7"
+    },
+>>>>>>> theirs
+    "jq@latest": {
+      "version": "1.7"
+    }
+  }
+}
+`
+	if diff := cmp.Diff(want, string(data)); diff != "" {
+		t.Errorf("wrong lockfile (-want +got):\n%s", diff)
+	}
+
+	// Without conflicts, the result is a valid lockfile.
+	data, err = Merge(base, base, theirs, nil).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := &File{}
+	if err := json.Unmarshal(data, merged); err != nil {
+		t.Fatalf("merged lockfile is invalid: %v", err)
+	}
+	if diff := cmp.Diff(theirs.Packages, merged.Packages); diff != "" {
+		t.Errorf("wrong packages (-want +got):\n%s", diff)
+	}
+}
+
+// TestMergeFetchesResolvedPackage merges lockfiles read like `devbox lock
+// merge` reads them, which have no project, and resolves the conflict with the
+// search service.
+func TestMergeFetchesResolvedPackage(t *testing.T) {
+	setupSearch(t, `{
+  "name": "go",
+  "version": "1.20.8",
+  "systems": {
+    "x86_64-linux": {
+      "commit_hash": "`+testCommit+`",
+      "attr_paths": ["go_1_20"],
+      "last_updated": 1693526400,
+      "store_hash": "0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c",
+      "store_name": "go",
+      "store_version": "1.20.8",
+      "version": "1.20.8"
+    }
+  }
+}`)
+
+	dir := t.TempDir()
+	files := make([]*File, 3)
+	for i, version := range []string{"1.20.5", "1.20.6", "1.20.7"} {
+		path := filepath.Join(dir, version)
+		content := `{
+  "lockfile_version": "2",
+  "packages": {
+    "go@1.20": {"resolved": "github:NixOS/nixpkgs/a#go", "version": "` + version + `"}
+  }
+}`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if files[i], err = ReadMergeFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base, ours, theirs := files[0], files[1], files[2]
+
+	result := Merge(base, ours, theirs, ours.FetchResolvedPackage)
+	if len(result.Conflicts) > 0 || len(result.Resolved) != 1 {
+		t.Fatalf("got resolved %v and conflicts %v, want go@1.20 to be resolved", result.Resolved, result.Conflicts)
+	}
+	want := &Package{
+		LastModified: "2023-09-01T00:00:00Z",
+		Resolved:     "github:NixOS/nixpkgs/" + testCommit + "#go_1_20",
+		Version:      "1.20.8",
+		Systems: map[string]*SystemInfo{
+			"x86_64-linux": {StorePath: "/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.8"},
+		},
+	}
+	if diff := cmp.Diff(want, result.File.Packages["go@1.20"]); diff != "" {
+		t.Errorf("wrong merged package (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+)
+
+const (
+	// MergeDriverName is the name of the merge driver in the git config and in
+	// .gitattributes.
+	MergeDriverName = "devbox-lock"
+	// mergeDriverCommand is run by git with the base, ours and theirs versions
+	// of devbox.lock. The result is written to ours (%A).
+	mergeDriverCommand   = "devbox lock merge %O %A %B"
+	mergeDriverAttribute = "devbox.lock merge=" + MergeDriverName
+)
+
+// InstallMergeDriver configures the git repository of the project in dir to
+// merge devbox.lock with `devbox lock merge`. It adds the driver to the
+// repository's git config, and marks devbox.lock to use it in the project's
+// .gitattributes. It returns false if .gitattributes already used the driver.
+func InstallMergeDriver(dir string) (bool, error) {
+	for key, value := range map[string]string{
+		"merge." + MergeDriverName + ".name":   "devbox.lock merge driver",
+		"merge." + MergeDriverName + ".driver": mergeDriverCommand,
+	} {
+		cmd := exec.Command("git", "-C", dir, "config", key, value)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return false, usererr.New(
+				"failed to configure git in %s: %s", dir, strings.TrimSpace(string(out)))
+		}
+	}
+
+	path := filepath.Join(dir, ".gitattributes")
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, errors.WithStack(err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == mergeDriverAttribute {
+			return false, nil
+		}
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, mergeDriverAttribute+"\n"...)
+	return true, errors.WithStack(os.WriteFile(path, data, 0644))
+}
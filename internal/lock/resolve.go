@@ -10,8 +10,10 @@ import (
 	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/boxcli/featureflag"
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/nix"
 	"go.jetpack.io/devbox/internal/searcher"
+	"go.jetpack.io/devbox/internal/semver"
 	"golang.org/x/exp/maps"
 )
 
@@ -27,17 +29,14 @@ func (l *File) FetchResolvedPackage(pkg string) (*Package, error) {
 		return nil, usererr.New("No version specified for %q.", name)
 	}
 
-	packageVersion, err := searcher.Client().Resolve(name, version)
+	packageVersion, err := resolveVersion(name, version)
 	if err != nil {
-		return nil, errors.Wrapf(nix.ErrPackageNotFound, "%s@%s", name, version)
+		return nil, err
 	}
 
-	sysInfos := map[string]*SystemInfo{}
-	if featureflag.RemoveNixpkgs.Enabled() {
-		sysInfos, err = buildLockSystemInfos(packageVersion)
-		if err != nil {
-			return nil, err
-		}
+	sysInfos, err := buildLockSystemInfos(packageVersion, l.BinaryCaches())
+	if err != nil {
+		return nil, err
 	}
 	packageInfo, err := selectForSystem(packageVersion)
 	if err != nil {
@@ -48,7 +47,25 @@ func (l *File) FetchResolvedPackage(pkg string) (*Package, error) {
 		return nil, fmt.Errorf("no attr paths found for package %q", name)
 	}
 
+	constraint := ""
+	if semver.IsRange(version) {
+		// Make sure that updates never move a package out of its range, even if
+		// the search service resolves it to another version.
+		c, err := semver.ParseConstraint(version)
+		if err != nil {
+			return nil, usererr.New("%v", err)
+		}
+		if !c.Check(packageInfo.Version) {
+			return nil, usererr.New(
+				"%s resolved to version %s, which is outside of the range %s",
+				name, packageInfo.Version, version,
+			)
+		}
+		constraint = version
+	}
+
 	return &Package{
+		Constraint: constraint,
 		LastModified: time.Unix(int64(packageInfo.LastUpdated), 0).UTC().
 			Format(time.RFC3339),
 		Resolved: fmt.Sprintf(
@@ -61,6 +78,24 @@ func (l *File) FetchResolvedPackage(pkg string) (*Package, error) {
 	}, nil
 }
 
+// resolveVersion resolves a package version with the search service, and
+// explains search errors to the user.
+func resolveVersion(name, version string) (*searcher.PackageVersion, error) {
+	packageVersion, err := searcher.Client().Resolve(name, version)
+	if errors.Is(err, searcher.ErrNotFound) {
+		return nil, errors.Wrapf(nix.ErrPackageNotFound, "%s@%s", name, version)
+	}
+	if errors.Is(err, searcher.ErrUnavailable) {
+		return nil, usererr.WithUserMessage(
+			err,
+			"Failed to resolve %s@%s because the package search service is unavailable. "+
+				"To resolve packages offline, see `devbox search index --help`.",
+			name, version,
+		)
+	}
+	return packageVersion, err
+}
+
 func selectForSystem(pkg *searcher.PackageVersion) (searcher.PackageInfo, error) {
 	currentSystem, err := nix.System()
 	if err != nil {
@@ -79,7 +114,10 @@ func selectForSystem(pkg *searcher.PackageVersion) (searcher.PackageInfo, error)
 	return maps.Values(pkg.Systems)[0], nil
 }
 
-func buildLockSystemInfos(pkg *searcher.PackageVersion) (map[string]*SystemInfo, error) {
+func buildLockSystemInfos(
+	pkg *searcher.PackageVersion,
+	caches nix.BinaryCaches,
+) (map[string]*SystemInfo, error) {
 	userSystem, err := nix.System()
 	if err != nil {
 		return nil, err
@@ -94,17 +132,36 @@ func buildLockSystemInfos(pkg *searcher.PackageVersion) (map[string]*SystemInfo,
 		}
 
 		storePath := nix.StorePath(sysInfo.StoreHash, sysInfo.StoreName, sysInfo.StoreVersion)
-		caStorePath := ""
+		info := &SystemInfo{StorePath: storePath}
 		if sysName == userSystem {
-			caStorePath, err = nix.ContentAddressedStorePath(storePath)
-			if err != nil {
-				return nil, err
+			if featureflag.RemoveNixpkgs.Enabled() {
+				info.CAStorePath, err = nix.ContentAddressedStorePath(storePath)
+				if err != nil {
+					return nil, err
+				}
 			}
+			recordNarHash(info, caches)
 		}
-		sysInfos[sysName] = &SystemInfo{
-			StorePath:   storePath,
-			CAStorePath: caStorePath,
-		}
+		sysInfos[sysName] = info
 	}
 	return sysInfos, nil
 }
+
+// recordNarHash records the NAR hash and size of the system's store path from
+// the first of the binary caches that has it, so that the package is verified
+// when it's first installed. If none of the caches has the path, the hash is
+// recorded when the package is installed instead.
+func recordNarHash(info *SystemInfo, caches nix.BinaryCaches) {
+	for _, cache := range caches.URLs() {
+		pathInfo, ok, err := nix.BinaryCachePathInfo(cache, info.StorePath)
+		if err != nil {
+			debug.Log("failed to get the NAR hash of %s from %s: %v", info.StorePath, cache, err)
+			continue
+		}
+		if ok {
+			info.NarHash = pathInfo.NarHash
+			info.NarSize = pathInfo.NarSize
+			return
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"go.jetpack.io/devbox/internal/boxcli/featureflag"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/searcher"
+)
+
+// MissingSystemsError is returned by ResolveSystems when a package has no
+// build for some of the requested systems.
+type MissingSystemsError struct {
+	Package string
+	Version string
+	Systems []string
+}
+
+func (e *MissingSystemsError) Error() string {
+	return fmt.Sprintf(
+		"%s (version %s) has no build for %s",
+		e.Package,
+		e.Version,
+		strings.Join(e.Systems, ", "),
+	)
+}
+
+// ResolveSystems fills in the system info of a versioned package for each of
+// the given systems that isn't locked yet, so that users on other systems
+// don't need to add theirs to the lockfile. The package keeps its locked
+// version and nixpkgs commit: it returns an error if the search service now
+// resolves the version to another commit, since the lockfile would otherwise
+// have store paths of a commit other than the one it resolves the package
+// from. Like FetchResolvedPackage, it records the NAR hash of each system from
+// the project's binary caches, and only computes the content-addressed store
+// path of the current system: computing it for another system would
+// substitute the package's whole closure. Users on other systems add theirs
+// with `devbox update`.
+//
+// It returns a *MissingSystemsError, without changing the package, if the
+// package has no build for some of the systems.
+func (l *File) ResolveSystems(pkg string, systems []string) error {
+	locked, err := l.Resolve(pkg)
+	if err != nil {
+		return err
+	}
+	unlocked := []string{}
+	for _, system := range systems {
+		if locked.Systems[system] == nil {
+			unlocked = append(unlocked, system)
+		}
+	}
+	if len(unlocked) == 0 {
+		return nil
+	}
+
+	name, _, _ := searcher.ParseVersionedPackage(pkg)
+	packageVersion, err := resolveVersion(name, locked.Version)
+	if err != nil {
+		return err
+	}
+	userSystem, err := nix.System()
+	if err != nil {
+		return err
+	}
+
+	lockedCommit := resolvedCommit(locked.Resolved)
+	resolved := map[string]*SystemInfo{}
+	missing := []string{}
+	for _, system := range unlocked {
+		info, ok := packageVersion.Systems[system]
+		if !ok || info.StoreHash == "" || info.StoreName == "" {
+			missing = append(missing, system)
+			continue
+		}
+		if info.CommitHash != lockedCommit {
+			return usererr.New(
+				"%s is locked to nixpkgs commit %s, but version %s of %s now resolves to "+
+					"commit %s. Run `devbox update %s` to lock it again",
+				pkg, lockedCommit, locked.Version, system, info.CommitHash, pkg,
+			)
+		}
+		storePath := nix.StorePath(info.StoreHash, info.StoreName, info.StoreVersion)
+
+		sysInfo := &SystemInfo{StorePath: storePath}
+		if system == userSystem && featureflag.RemoveNixpkgs.Enabled() {
+			if sysInfo.CAStorePath, err = nix.ContentAddressedStorePath(storePath); err != nil {
+				return err
+			}
+		}
+		recordNarHash(sysInfo, l.BinaryCaches())
+		resolved[system] = sysInfo
+	}
+	if len(missing) > 0 {
+		return &MissingSystemsError{Package: pkg, Version: locked.Version, Systems: missing}
+	}
+
+	if locked.Systems == nil {
+		locked.Systems = map[string]*SystemInfo{}
+	}
+	maps.Copy(locked.Systems, resolved)
+	return nil
+}
+
+// resolvedCommit returns the nixpkgs commit of a resolved package like
+// github:NixOS/nixpkgs/<commit>#<attr path>.
+func resolvedCommit(resolved string) string {
+	ref, _, _ := strings.Cut(resolved, "#")
+	return ref[strings.LastIndexByte(ref, '/')+1:]
+}
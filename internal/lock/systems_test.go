@@ -0,0 +1,134 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/envir"
+)
+
+// goVersion is the response of the search service for go@1.20.5, which has no
+// build for aarch64-linux.
+const goVersion = `{
+  "name": "go",
+  "version": "1.20.5",
+  "systems": {
+    "x86_64-linux": {"commit_hash": "` + testCommit + `", "store_hash": "0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c", "store_name": "go", "store_version": "1.20.5"},
+    "aarch64-darwin": {"commit_hash": "` + testCommit + `", "store_hash": "1m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c", "store_name": "go", "store_version": "1.20.5"}
+  }
+}`
+
+// setupSearch points the search service to a server that resolves every
+// package to body, and makes nix unavailable so that NAR hashes aren't fetched
+// from the binary cache.
+func setupSearch(t *testing.T, body string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv(envir.DevboxSearchHost, server.URL)
+	t.Setenv(envir.DevboxSearchIndex, filepath.Join(t.TempDir(), "no-index"))
+	t.Setenv(envir.XDGCacheHome, t.TempDir())
+	t.Setenv("__DEVBOX_NIX_SYSTEM", "x86_64-linux")
+	t.Setenv("PATH", t.TempDir())
+}
+
+func TestResolveSystems(t *testing.T) {
+	setupSearch(t, goVersion)
+	const narHash = "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	lockfile := &File{
+		devboxProject: &testProject{packages: []string{"go@1.20"}},
+		Packages: map[string]*Package{
+			"go@1.20": {
+				Resolved: "github:NixOS/nixpkgs/" + testCommit + "#go_1_20",
+				Version:  "1.20.5",
+				Systems: map[string]*SystemInfo{
+					"x86_64-linux": {
+						StorePath: "/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5",
+						NarHash:   narHash,
+						NarSize:   120,
+					},
+				},
+			},
+		},
+	}
+
+	err := lockfile.ResolveSystems("go@1.20", []string{"x86_64-linux", "aarch64-darwin"})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	want := map[string]*SystemInfo{
+		// Systems that are already locked are kept.
+		"x86_64-linux": {
+			StorePath: "/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5",
+			NarHash:   narHash,
+			NarSize:   120,
+		},
+		"aarch64-darwin": {StorePath: "/nix/store/1m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5"},
+	}
+	if diff := cmp.Diff(want, lockfile.Packages["go@1.20"].Systems); diff != "" {
+		t.Errorf("wrong systems (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveSystemsMissingSystem(t *testing.T) {
+	setupSearch(t, goVersion)
+	lockfile := &File{
+		devboxProject: &testProject{packages: []string{"go@1.20"}},
+		Packages: map[string]*Package{
+			"go@1.20": {
+				Resolved: "github:NixOS/nixpkgs/" + testCommit + "#go_1_20",
+				Version:  "1.20.5",
+			},
+		},
+	}
+
+	err := lockfile.ResolveSystems("go@1.20", []string{"aarch64-linux", "aarch64-darwin"})
+	var missingErr *MissingSystemsError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("got error %v, want a *MissingSystemsError", err)
+	}
+	want := &MissingSystemsError{Package: "go@1.20", Version: "1.20.5", Systems: []string{"aarch64-linux"}}
+	if diff := cmp.Diff(want, missingErr); diff != "" {
+		t.Errorf("wrong error (-want +got):\n%s", diff)
+	}
+	if got := missingErr.Error(); got != "go@1.20 (version 1.20.5) has no build for aarch64-linux" {
+		t.Errorf("got error message %q", got)
+	}
+	// The package isn't changed, even for the systems that have a build.
+	if systems := lockfile.Packages["go@1.20"].Systems; systems != nil {
+		t.Errorf("got systems %v, want none to be locked", systems)
+	}
+}
+
+func TestResolveSystemsOtherCommit(t *testing.T) {
+	setupSearch(t, goVersion)
+	lockfile := &File{
+		devboxProject: &testProject{packages: []string{"go@1.20"}},
+		Packages: map[string]*Package{
+			"go@1.20": {
+				Resolved: "github:NixOS/nixpkgs/8d4d822bc0efa9de6eddc79cb0d82897a9baa750#go_1_20",
+				Version:  "1.20.5",
+			},
+		},
+	}
+
+	err := lockfile.ResolveSystems("go@1.20", []string{"aarch64-darwin"})
+	if err == nil || !strings.Contains(err.Error(), "devbox update go@1.20") {
+		t.Fatalf("got error %v, want an error asking to update go@1.20", err)
+	}
+	if systems := lockfile.Packages["go@1.20"].Systems; systems != nil {
+		t.Errorf("got systems %v, want none to be locked from another commit", systems)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/searcher"
+	"go.jetpack.io/devbox/internal/semver"
+)
+
+// Kinds of problems that Verify finds.
+const (
+	// ProblemStale is an entry for a package or include that isn't in
+	// devbox.json anymore.
+	ProblemStale = "stale"
+	// ProblemMissing is a package or remote include in devbox.json that isn't
+	// locked.
+	ProblemMissing = "missing"
+	// ProblemMalformed is an entry whose fields don't have the expected format,
+	// like a resolved flake reference that isn't pinned to a nixpkgs commit.
+	ProblemMalformed = "malformed"
+	// ProblemStorePath is a store path that doesn't have the format of a nix
+	// store path, or doesn't match the version of the package.
+	ProblemStorePath = "store_path"
+	// ProblemPluginVersion is a plugin whose version changed since its files
+	// were created.
+	ProblemPluginVersion = "plugin_version"
+	// ProblemLockfileVersion is a lockfile version that this version of devbox
+	// doesn't know.
+	ProblemLockfileVersion = "lockfile_version"
+)
+
+// Problem is an inconsistency in the lockfile.
+type Problem struct {
+	// Package is the key of the entry, which is empty for problems with the
+	// whole lockfile.
+	Package string `json:"package,omitempty"`
+	System  string `json:"system,omitempty"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	prefix := p.Package
+	if p.System != "" {
+		prefix += " (" + p.System + ")"
+	}
+	if prefix == "" {
+		return p.Message
+	}
+	return prefix + ": " + p.Message
+}
+
+var (
+	// nixpkgsRefRegex matches the resolved reference of versioned and legacy
+	// packages, like github:NixOS/nixpkgs/<commit>#go_1_20.
+	nixpkgsRefRegex = regexp.MustCompile(`^github:NixOS/nixpkgs/[0-9a-f]{40}#[^#\s]+$`)
+	// storePathRegex matches /nix/store/<hash>-<name>. The hash uses the nix
+	// base32 alphabet, which leaves out e, o, t and u.
+	storePathRegex   = regexp.MustCompile(`^/nix/store/[0-9a-df-np-sv-z]{32}-([A-Za-z0-9+\-._?=]+)$`)
+	contentHashRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	narHashRegex     = regexp.MustCompile(`^sha256-[A-Za-z0-9+/]{43}=$`)
+)
+
+// Verify checks that the lockfile matches devbox.json, and that the fields of
+// each entry are well formed and consistent with each other. It doesn't
+// resolve anything, so it works offline. Problems are sorted by package.
+func (l *File) Verify() []Problem {
+	problems := []Problem{}
+	if l.LockFileVersion != lockFileVersion {
+		problems = append(problems, Problem{
+			Kind: ProblemLockfileVersion,
+			Message: fmt.Sprintf(
+				"lockfile version %q is not supported, expected %q",
+				l.LockFileVersion,
+				lockFileVersion,
+			),
+		})
+	}
+
+	packages := l.devboxProject.Packages()
+	includes := l.devboxProject.IncludeRefs()
+	for _, pkg := range packages {
+		if _, ok := l.Packages[pkg]; !ok {
+			problems = append(problems, Problem{
+				Package: pkg,
+				Kind:    ProblemMissing,
+				Message: "package is in devbox.json but not in devbox.lock",
+			})
+		}
+	}
+
+	keys := maps.Keys(l.Packages)
+	slices.Sort(keys)
+	for _, key := range keys {
+		entry := l.Packages[key]
+		switch {
+		case slices.Contains(packages, key):
+			problems = append(problems, verifyPackage(key, entry)...)
+		case slices.Contains(includes, key):
+			problems = append(problems, verifyInclude(key, entry)...)
+		default:
+			problems = append(problems, Problem{
+				Package: key,
+				Kind:    ProblemStale,
+				Message: "entry is not a package or include in devbox.json",
+			})
+		}
+	}
+
+	slices.SortStableFunc(problems, func(a, b Problem) bool {
+		return a.Package < b.Package
+	})
+	return problems
+}
+
+func verifyPackage(pkg string, entry *Package) []Problem {
+	problems := []Problem{}
+	malformed := func(format string, args ...any) {
+		problems = append(problems, Problem{
+			Package: pkg,
+			Kind:    ProblemMalformed,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	if entry == nil {
+		malformed("entry is null")
+		return problems
+	}
+
+	_, version, isVersioned := searcher.ParseVersionedPackage(pkg)
+	if isVersioned || IsLegacyPackage(pkg) {
+		if !nixpkgsRefRegex.MatchString(entry.Resolved) {
+			malformed(
+				"resolved %q is not of the form github:NixOS/nixpkgs/<commit>#<attribute>",
+				entry.Resolved,
+			)
+		}
+	}
+	if isVersioned && entry.Version == "" {
+		malformed("versioned package has no version")
+	}
+	if entry.Constraint != "" {
+		if entry.Constraint != version {
+			malformed("constraint %q doesn't match the package's version range %q", entry.Constraint, version)
+		} else if c, err := semver.ParseConstraint(entry.Constraint); err != nil {
+			malformed("%v", err)
+		} else if entry.Version != "" && !c.Check(entry.Version) {
+			malformed("version %s is outside of the range %s", entry.Version, entry.Constraint)
+		}
+	}
+	if entry.LastModified != "" {
+		if _, err := time.Parse(time.RFC3339, entry.LastModified); err != nil {
+			malformed("last_modified %q is not an RFC 3339 date", entry.LastModified)
+		}
+	}
+
+	systems := maps.Keys(entry.Systems)
+	slices.Sort(systems)
+	for _, system := range systems {
+		info := entry.Systems[system]
+		if info == nil {
+			continue
+		}
+		problems = append(problems, verifyStorePath(pkg, system, "store_path", info.StorePath, entry.Version)...)
+		problems = append(problems, verifyStorePath(pkg, system, "ca_store_path", info.CAStorePath, entry.Version)...)
+		if info.NarHash != "" && !narHashRegex.MatchString(info.NarHash) {
+			problems = append(problems, Problem{
+				Package: pkg,
+				System:  system,
+				Kind:    ProblemMalformed,
+				Message: fmt.Sprintf("nar_hash %q is not a sha256 SRI hash", info.NarHash),
+			})
+		}
+	}
+	return problems
+}
+
+func verifyStorePath(pkg, system, field, storePath, version string) []Problem {
+	if storePath == "" {
+		return nil
+	}
+	problem := func(format string, args ...any) []Problem {
+		return []Problem{{
+			Package: pkg,
+			System:  system,
+			Kind:    ProblemStorePath,
+			Message: fmt.Sprintf(format, args...),
+		}}
+	}
+
+	matches := storePathRegex.FindStringSubmatch(storePath)
+	if matches == nil {
+		return problem("%s %q is not of the form /nix/store/<hash>-<name>", field, storePath)
+	}
+	// Store path names end with the version of the package, optionally
+	// followed by an output name like "-bin".
+	if version != "" && !strings.Contains(matches[1], "-"+version) {
+		return problem("%s %q doesn't match version %s", field, storePath, version)
+	}
+	return nil
+}
+
+func verifyInclude(include string, entry *Package) []Problem {
+	if entry == nil || entry.ContentHash == "" || contentHashRegex.MatchString(entry.ContentHash) {
+		return nil
+	}
+	return []Problem{{
+		Package: include,
+		Kind:    ProblemMalformed,
+		Message: fmt.Sprintf("content_hash %q is not a sha256 hex digest", entry.ContentHash),
+	}}
+}
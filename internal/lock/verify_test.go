@@ -0,0 +1,192 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/nix"
+)
+
+type testProject struct {
+	packages []string
+	includes []string
+}
+
+func (p *testProject) BinaryCaches() nix.BinaryCaches { return nix.BinaryCaches{} }
+func (p *testProject) ConfigHash() (string, error)    { return "", nil }
+func (p *testProject) IncludeRefs() []string          { return p.includes }
+func (p *testProject) NixPkgsCommitHash() string      { return "" }
+func (p *testProject) Packages() []string             { return p.packages }
+func (p *testProject) ProjectDir() string             { return "" }
+
+const testCommit = "5233fd2ba76a3accb5aaa999c00509a11fd0793c"
+
+func TestVerify(t *testing.T) {
+	valid := &Package{
+		LastModified: "2023-06-01T12:00:00Z",
+		Resolved:     "github:NixOS/nixpkgs/" + testCommit + "#go_1_20",
+		Version:      "1.20.5",
+		Systems: map[string]*SystemInfo{
+			"x86_64-linux": {
+				StorePath: "/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5",
+				NarHash:   "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+				NarSize:   211616,
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		project  *testProject
+		packages map[string]*Package
+		want     []Problem
+	}{
+		{
+			name:    "valid",
+			project: &testProject{packages: []string{"go@1.20", "hello"}},
+			packages: map[string]*Package{
+				"go@1.20": valid,
+				"hello":   {Resolved: "github:NixOS/nixpkgs/" + testCommit + "#hello"},
+			},
+			want: []Problem{},
+		},
+		{
+			name: "missing and stale",
+			project: &testProject{
+				packages: []string{"go@1.20", "jq@latest"},
+				includes: []string{"github:org/repo"},
+			},
+			packages: map[string]*Package{
+				"go@1.20":         valid,
+				"ripgrep@latest":  valid,
+				"github:org/repo": {ContentHash: "abc"},
+			},
+			want: []Problem{
+				{
+					Package: "github:org/repo",
+					Kind:    ProblemMalformed,
+					Message: `content_hash "abc" is not a sha256 hex digest`,
+				},
+				{
+					Package: "jq@latest",
+					Kind:    ProblemMissing,
+					Message: "package is in devbox.json but not in devbox.lock",
+				},
+				{
+					Package: "ripgrep@latest",
+					Kind:    ProblemStale,
+					Message: "entry is not a package or include in devbox.json",
+				},
+			},
+		},
+		{
+			name:    "malformed",
+			project: &testProject{packages: []string{"go@1.20"}},
+			packages: map[string]*Package{
+				"go@1.20": {
+					LastModified: "yesterday",
+					Resolved:     "github:NixOS/nixpkgs/main#go",
+					Systems: map[string]*SystemInfo{
+						"x86_64-linux": {
+							StorePath:   "/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.21.0",
+							CAStorePath: "/nix/store/go-1.20.5",
+							NarHash:     "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+						},
+					},
+				},
+			},
+			want: []Problem{
+				{
+					Package: "go@1.20",
+					Kind:    ProblemMalformed,
+					Message: `resolved "github:NixOS/nixpkgs/main#go" is not of the form github:NixOS/nixpkgs/<commit>#<attribute>`,
+				},
+				{
+					Package: "go@1.20",
+					Kind:    ProblemMalformed,
+					Message: "versioned package has no version",
+				},
+				{
+					Package: "go@1.20",
+					Kind:    ProblemMalformed,
+					Message: `last_modified "yesterday" is not an RFC 3339 date`,
+				},
+				{
+					Package: "go@1.20",
+					System:  "x86_64-linux",
+					Kind:    ProblemStorePath,
+					Message: `ca_store_path "/nix/store/go-1.20.5" is not of the form /nix/store/<hash>-<name>`,
+				},
+				{
+					Package: "go@1.20",
+					System:  "x86_64-linux",
+					Kind:    ProblemMalformed,
+					Message: `nar_hash "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" is not a sha256 SRI hash`,
+				},
+			},
+		},
+		{
+			name:    "store path version",
+			project: &testProject{packages: []string{"go@1.20"}},
+			packages: map[string]*Package{
+				"go@1.20": {
+					Resolved: valid.Resolved,
+					Version:  "1.20.7",
+					Systems:  valid.Systems,
+				},
+			},
+			want: []Problem{
+				{
+					Package: "go@1.20",
+					System:  "x86_64-linux",
+					Kind:    ProblemStorePath,
+					Message: `store_path "/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5" doesn't match version 1.20.7`,
+				},
+			},
+		},
+		{
+			name:    "version outside of range",
+			project: &testProject{packages: []string{"go@^1.20", "go@>=1.19 <1.21"}},
+			packages: map[string]*Package{
+				"go@^1.20": {
+					Constraint: "^1.20",
+					Resolved:   valid.Resolved,
+					Version:    "2.0.1",
+				},
+				"go@>=1.19 <1.21": {
+					Constraint: "^1.19",
+					Resolved:   valid.Resolved,
+					Version:    "1.20.5",
+				},
+			},
+			want: []Problem{
+				{
+					Package: "go@>=1.19 <1.21",
+					Kind:    ProblemMalformed,
+					Message: `constraint "^1.19" doesn't match the package's version range ">=1.19 <1.21"`,
+				},
+				{
+					Package: "go@^1.20",
+					Kind:    ProblemMalformed,
+					Message: "version 2.0.1 is outside of the range ^1.20",
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lockfile := &File{
+				devboxProject:   test.project,
+				LockFileVersion: lockFileVersion,
+				Packages:        test.packages,
+			}
+			if diff := cmp.Diff(test.want, lockfile.Verify()); diff != "" {
+				t.Errorf("wrong problems (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
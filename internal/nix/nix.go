@@ -15,6 +15,7 @@ import (
 
 	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/boxcli/featureflag"
+	"golang.org/x/exp/slices"
 
 	"go.jetpack.io/devbox/internal/debug"
 )
@@ -34,6 +35,7 @@ type Variable struct {
 }
 
 type PrintDevEnvArgs struct {
+	BinaryCaches         BinaryCaches
 	FlakesFilePath       string
 	PrintDevEnvCachePath string
 	UsePrintDevEnvCache  bool
@@ -66,6 +68,7 @@ func (*Nix) PrintDevEnv(ctx context.Context, args *PrintDevEnvArgs) (*PrintDevEn
 			args.FlakesFilePath,
 		)
 		cmd.Args = append(cmd.Args, ExperimentalFlags()...)
+		cmd.Args = append(cmd.Args, args.BinaryCaches.Flags()...)
 		cmd.Args = append(cmd.Args, "--json")
 		debug.Log("Running print-dev-env cmd: %s\n", cmd)
 		data, err = cmd.Output()
@@ -114,6 +117,39 @@ func ExperimentalFlags() []string {
 	}
 }
 
+// BinaryCaches are the binary caches of a project, which are used in addition
+// to those of the nix configuration, and the keys that sign their packages.
+type BinaryCaches struct {
+	Substituters      []string
+	TrustedPublicKeys []string
+}
+
+// Flags returns the options that add the caches to a nix command. Nix only
+// uses them if the user is trusted by the nix daemon, or if they are listed in
+// its trusted-substituters.
+func (c BinaryCaches) Flags() []string {
+	flags := []string{}
+	if len(c.Substituters) > 0 {
+		flags = append(flags, "--option", "extra-substituters", strings.Join(c.Substituters, " "))
+	}
+	if len(c.TrustedPublicKeys) > 0 {
+		flags = append(flags, "--option", "extra-trusted-public-keys", strings.Join(c.TrustedPublicKeys, " "))
+	}
+	return flags
+}
+
+// URLs returns the caches from which to fetch packages' binaries, in the order
+// they are tried: the project's substituters, then DefaultBinaryCache.
+func (c BinaryCaches) URLs() []string {
+	urls := []string{}
+	for _, url := range append(slices.Clone(c.Substituters), DefaultBinaryCache) {
+		if !slices.Contains(urls, url) {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
 var cachedSystem string
 
 func System() (string, error) {
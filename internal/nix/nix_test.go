@@ -0,0 +1,41 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package nix
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBinaryCachesFlags(t *testing.T) {
+	if flags := (BinaryCaches{}).Flags(); len(flags) != 0 {
+		t.Errorf("got flags %q without binary caches, want none", flags)
+	}
+
+	caches := BinaryCaches{
+		Substituters:      []string{"file:///tmp/cache", "https://cache.example.com"},
+		TrustedPublicKeys: []string{"local-1:abc="},
+	}
+	flags := strings.Join(caches.Flags(), " ")
+	want := "--option extra-substituters file:///tmp/cache https://cache.example.com " +
+		"--option extra-trusted-public-keys local-1:abc="
+	if flags != want {
+		t.Errorf("got flags %q, want %q", flags, want)
+	}
+}
+
+func TestBinaryCachesURLs(t *testing.T) {
+	caches := BinaryCaches{
+		Substituters: []string{"https://cache.example.com", DefaultBinaryCache},
+	}
+	want := []string{"https://cache.example.com", DefaultBinaryCache}
+	if diff := cmp.Diff(want, caches.URLs()); diff != "" {
+		t.Errorf("wrong URLs (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{DefaultBinaryCache}, (BinaryCaches{}).URLs()); diff != "" {
+		t.Errorf("wrong URLs without substituters (-want +got):\n%s", diff)
+	}
+}
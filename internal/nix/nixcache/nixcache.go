@@ -0,0 +1,142 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package nixcache queries Nix binary caches.
+package nixcache
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	s3client "go.jetpack.io/devbox/internal/pullbox/s3"
+)
+
+// Cache is a binary cache, in the format of https://cache.nixos.org, that
+// stores a <hash>.narinfo file for each store path it has.
+type Cache interface {
+	// Has returns true if the cache has the store path.
+	Has(ctx context.Context, storePath string) (bool, error)
+}
+
+// Open returns the binary cache at a store URL, which is a file://, s3://,
+// http:// or https:// URL. For s3:// URLs, the region and endpoint query
+// parameters are used like nix does, and credentials come from the default
+// AWS configuration.
+func Open(ctx context.Context, storeURL string) (Cache, error) {
+	u, err := url.Parse(storeURL)
+	if err != nil {
+		return nil, usererr.New("invalid binary cache URL %q: %v", storeURL, err)
+	}
+	switch u.Scheme {
+	case "file":
+		return &fileCache{dir: u.Path}, nil
+	case "http", "https":
+		u.RawQuery = ""
+		return &httpCache{url: strings.TrimSuffix(u.String(), "/"), client: http.DefaultClient}, nil
+	case "s3":
+		client, err := s3client.NewClient(ctx, u.Query().Get("region"), u.Query().Get("endpoint"))
+		if err != nil {
+			return nil, err
+		}
+		return &s3Cache{bucket: u.Host, client: client}, nil
+	default:
+		return nil, usererr.New(
+			"unsupported binary cache URL %q: use a file://, s3://, http:// or https:// URL",
+			storeURL,
+		)
+	}
+}
+
+// narinfoName returns the name of the narinfo file of a store path, which is
+// the hash part of the path followed by .narinfo.
+func narinfoName(storePath string) (string, error) {
+	base := filepath.Base(storePath)
+	if len(base) < 33 || base[32] != '-' {
+		return "", errors.Errorf("invalid store path %s", storePath)
+	}
+	return base[:32] + ".narinfo", nil
+}
+
+type fileCache struct {
+	dir string
+}
+
+func (c *fileCache) Has(_ context.Context, storePath string) (bool, error) {
+	name, err := narinfoName(storePath)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(filepath.Join(c.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+type httpCache struct {
+	url    string
+	client *http.Client
+}
+
+func (c *httpCache) Has(ctx context.Context, storePath string) (bool, error) {
+	name, err := narinfoName(storePath)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.url+"/"+name, nil)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	// Caches backed by S3 return 403 for missing files.
+	case http.StatusNotFound, http.StatusForbidden:
+		return false, nil
+	default:
+		return false, errors.Errorf("HEAD %s: unexpected status %s", req.URL, resp.Status)
+	}
+}
+
+type s3Cache struct {
+	bucket string
+	client *s3.Client
+}
+
+func (c *s3Cache) Has(ctx context.Context, storePath string) (bool, error) {
+	name, err := narinfoName(storePath)
+	if err != nil {
+		return false, err
+	}
+	_, err = c.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(name),
+	})
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check s3://%s/%s", c.bucket, name)
+	}
+	return true, nil
+}
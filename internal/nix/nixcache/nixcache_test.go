@@ -0,0 +1,69 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package nixcache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	cachedPath  = "/nix/store/r2jd6ygnmirm2g803mksqqjm4y39yi6i-git-2.33.1"
+	missingPath = "/nix/store/ldbhlwhh39wha58rm61bkiiwm6j7211j-git-2.33.1"
+)
+
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "r2jd6ygnmirm2g803mksqqjm4y39yi6i.narinfo"), nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := Open(context.Background(), "file://"+dir+"?secret-key=/tmp/key.sec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCache(t, cache)
+}
+
+func TestHTTPCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("got method %s, want HEAD", r.Method)
+		}
+		if r.URL.Path != "/cache/r2jd6ygnmirm2g803mksqqjm4y39yi6i.narinfo" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := Open(context.Background(), server.URL+"/cache/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCache(t, cache)
+}
+
+func testCache(t *testing.T, cache Cache) {
+	t.Helper()
+	ctx := context.Background()
+	if has, err := cache.Has(ctx, cachedPath); err != nil || !has {
+		t.Errorf("Has(%s) = %v, %v, want true", cachedPath, has, err)
+	}
+	if has, err := cache.Has(ctx, missingPath); err != nil || has {
+		t.Errorf("Has(%s) = %v, %v, want false", missingPath, has, err)
+	}
+	if _, err := cache.Has(ctx, "/nix/store/invalid"); err == nil {
+		t.Error("got nil error for an invalid store path, want error")
+	}
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	if _, err := Open(context.Background(), "ssh://cache.example.com"); err == nil {
+		t.Error("got nil error for an ssh:// cache, want error")
+	}
+}
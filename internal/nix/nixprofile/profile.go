@@ -69,18 +69,13 @@ func ProfileListIndex(args *ProfileListIndexArgs) (int, error) {
 		}
 	}
 
-	inCache, err := args.Input.IsInBinaryCache()
+	storePath, isLocked, err := args.Input.LockedStorePath()
 	if err != nil {
 		return -1, err
 	}
-	if inCache {
-		// TODO savil: change to ContentAddressedPath?
-		pathInStore, err := args.Input.InputAddressedPath()
-		if err != nil {
-			return -1, err
-		}
+	if isLocked {
 		for _, item := range list {
-			if pathInStore == item.nixStorePath {
+			if storePath == item.nixStorePath {
 				return item.index, nil
 			}
 		}
@@ -183,6 +178,18 @@ func (item *NixProfileListItem) AttributePath() (string, error) {
 	return attrPath, nil
 }
 
+// IsStorePathOf returns true if the item's store path is the store path that
+// the lockfile records for the package. Packages that were installed from the
+// binary cache by store path have no flake reference, so this is the only way
+// to match them.
+func (item *NixProfileListItem) IsStorePathOf(pkg *devpkg.Package) (bool, error) {
+	storePath, ok, err := pkg.LockedStorePath()
+	if err != nil || !ok {
+		return false, err
+	}
+	return item.nixStorePath == storePath, nil
+}
+
 // ToPackage constructs a nix.Package using the unlocked reference
 func (item *NixProfileListItem) ToPackage(locker lock.Locker) *devpkg.Package {
 	return devpkg.PackageFromString(item.unlockedReference, locker)
@@ -210,7 +217,7 @@ type ProfileInstallArgs struct {
 func ProfileInstall(args *ProfileInstallArgs) error {
 	input := devpkg.PackageFromString(args.Package, args.Lockfile)
 
-	inCache, err := input.IsInBinaryCache()
+	inCache, err := input.IsSubstitutable()
 	if err != nil {
 		return err
 	}
@@ -233,7 +240,12 @@ func ProfileInstall(args *ProfileInstallArgs) error {
 		return err
 	}
 
-	err = nix.ProfileInstall(args.Writer, args.ProfilePath, installable)
+	err = nix.ProfileInstall(
+		args.Writer,
+		args.ProfilePath,
+		installable,
+		args.Lockfile.BinaryCaches(),
+	)
 	if err != nil {
 		fmt.Fprintf(args.Writer, "%s: ", stepMsg)
 		color.New(color.FgRed).Fprintf(args.Writer, "Fail\n")
@@ -241,6 +241,30 @@ func (p Package) String() string {
 	return p.StoreName
 }
 
+// Size returns the total size in bytes of the package's regular files. It
+// doesn't include the size of its dependencies.
+func (p *Package) Size() (int64, error) {
+	var size int64
+	err := fs.WalkDir(p, ".", func(entryPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error computing the size of %s: %v", p.StoreName, err)
+	}
+	return size, nil
+}
+
 // TopologicalSort resolves the dependency tree for a package and returns it as
 // a slice of packages in topological order.
 func TopologicalSort(pkg *Package) []*Package {
@@ -6,6 +6,7 @@ package nixstore
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -86,3 +87,53 @@ func unmarshalNixPathInfoOutput(t *testing.T, got *Package) map[string][]string
 	}
 	return depsByPackage
 }
+
+func TestPackageSize(t *testing.T) {
+	const (
+		lib = "1111111111111111111111111111111a-lib-1.0"
+		bin = "2222222222222222222222222222222b-bin-1.0"
+	)
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		lib + "/lib/liba.so": "library",
+		bin + "/bin/a":       "#!/bin/sh\nexec " + dir + "/" + lib + "/lib/liba.so\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, lib, "lib"), filepath.Join(dir, bin, "lib")); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Local(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := store.Package(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closure := TopologicalSort(pkg)
+	if len(closure) != 2 || closure[0].StoreName != lib || closure[1].StoreName != bin {
+		t.Fatalf("got closure %v, want [%s %s]", closure, lib, bin)
+	}
+
+	wantSizes := map[string]int64{
+		lib: int64(len("library")),
+		bin: int64(len("#!/bin/sh\nexec " + dir + "/" + lib + "/lib/liba.so\n")),
+	}
+	for _, p := range closure {
+		size, err := p.Size()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != wantSizes[p.StoreName] {
+			t.Errorf("got size %d for %s, want %d", size, p, wantSizes[p.StoreName])
+		}
+	}
+}
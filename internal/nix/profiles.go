@@ -11,8 +11,13 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
 	"go.jetpack.io/devbox/internal/redact"
 )
 
@@ -48,7 +53,12 @@ func ProfileList(writer io.Writer, profilePath string) ([]string, error) {
 	return lines, nil
 }
 
-func ProfileInstall(writer io.Writer, profilePath string, installable string) error {
+func ProfileInstall(
+	writer io.Writer,
+	profilePath string,
+	installable string,
+	caches BinaryCaches,
+) error {
 
 	cmd := command(
 		"profile", "install",
@@ -60,6 +70,7 @@ func ProfileInstall(writer io.Writer, profilePath string, installable string) er
 		"--priority", nextPriority(profilePath),
 		installable,
 	)
+	cmd.Args = append(cmd.Args, caches.Flags()...)
 	cmd.Env = allowUnfreeEnv()
 
 	// If nix profile install runs as tty, the output is much nicer. If we ever
@@ -90,6 +101,47 @@ func ProfileRemove(profilePath string, indexes []string) error {
 	return nil
 }
 
+// ProfileGenerations returns the paths of the generations of a profile, like
+// default-1-link, sorted by generation number. The last one is usually the
+// current generation.
+func ProfileGenerations(profilePath string) ([]string, error) {
+	links, err := filepath.Glob(profilePath + "-*-link")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	generations := map[string]int{}
+	for _, link := range links {
+		number := strings.TrimSuffix(strings.TrimPrefix(link, profilePath+"-"), "-link")
+		if n, err := strconv.Atoi(number); err == nil {
+			generations[link] = n
+		}
+	}
+	paths := maps.Keys(generations)
+	slices.SortFunc(paths, func(a, b string) bool {
+		return generations[a] < generations[b]
+	})
+	return paths, nil
+}
+
+// ProfileWipeHistory deletes the generations of a profile except the current
+// one, so that nix can garbage collect the packages that only they use.
+func ProfileWipeHistory(profilePath string) error {
+	out, err := command("profile", "wipe-history", "--profile", profilePath).CombinedOutput()
+	if err != nil {
+		return redact.Errorf("error running \"nix profile wipe-history\": %s: %w", out, err)
+	}
+	return nil
+}
+
+// StoreGC deletes the store paths that aren't reachable from a garbage
+// collector root, like the generations of profiles.
+func StoreGC(w io.Writer) error {
+	cmd := command("store", "gc")
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return errors.Wrap(cmd.Run(), "nix store gc")
+}
+
 type manifest struct {
 	Elements []struct {
 		Priority int `json:"priority"`
@@ -15,8 +15,29 @@ import (
 )
 
 func RunScript(projectDir string, cmdWithArgs string, env map[string]string) error {
+	cmd, err := ScriptCommand(projectDir, cmdWithArgs, env)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	debug.Log("Executing: %v", cmd.Args)
+	// Report error as exec error when executing scripts.
+	return usererr.NewExecError(cmd.Run())
+}
+
+// ScriptCommand returns a command that runs cmdWithArgs with sh in projectDir,
+// with only the given env. The caller is responsible for setting up its
+// standard streams.
+func ScriptCommand(
+	projectDir string,
+	cmdWithArgs string,
+	env map[string]string,
+) (*exec.Cmd, error) {
 	if cmdWithArgs == "" {
-		return errors.New("attempted to run an empty command or script")
+		return nil, errors.New("attempted to run an empty command or script")
 	}
 
 	envPairs := []string{}
@@ -29,11 +50,5 @@ func RunScript(projectDir string, cmdWithArgs string, env map[string]string) err
 	cmd := exec.Command(shPath, "-c", cmdWithArgs)
 	cmd.Env = envPairs
 	cmd.Dir = projectDir
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	debug.Log("Executing: %v", cmd.Args)
-	// Report error as exec error when executing scripts.
-	return usererr.NewExecError(cmd.Run())
+	return cmd, nil
 }
@@ -99,3 +99,34 @@ func searchSystem(url string, system string) map[string]*Info {
 	}
 	return parseSearchResults(out)
 }
+
+// SearchAll returns every package of a flake for the given system, keyed by
+// attribute path. Unlike Search, it reports errors, since they can't be caused
+// by a package that doesn't exist.
+func SearchAll(flakeRef string, system string) (map[string]*Info, error) {
+	cmd := command("search", "--json", flakeRef, "^", "--system", system)
+	cmd.Env = allowUnfreeEnv()
+	cmd.Stderr = os.Stderr
+	debug.Log("running command: %s\n", cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the packages of %s for %s", flakeRef, system)
+	}
+
+	var results map[string]struct {
+		PName   string `json:"pname"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	infos := map[string]*Info{}
+	for key, result := range results {
+		infos[key] = &Info{
+			AttributeKey: key,
+			PName:        result.PName,
+			Version:      result.Version,
+		}
+	}
+	return infos, nil
+}
@@ -1,11 +1,20 @@
 package nix
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/debug"
 )
 
 func StorePath(hash, name, version string) string {
@@ -17,6 +26,9 @@ func StorePath(hash, name, version string) string {
 	return filepath.Join("/nix/store", storeDir)
 }
 
+// DefaultBinaryCache is the binary cache that nix uses by default.
+const DefaultBinaryCache = "https://cache.nixos.org"
+
 // ContentAddressedStorePath takes a store path and returns the content-addressed store path.
 func ContentAddressedStorePath(storePath string) (string, error) {
 	cmd := command("store", "make-content-addressed", storePath, "--json")
@@ -42,3 +54,175 @@ func ContentAddressedStorePath(storePath string) (string, error) {
 	}
 	return caStorePath, nil
 }
+
+// PathInfo is the metadata of a path in the local nix store.
+type PathInfo struct {
+	Path string `json:"path"`
+	// NarHash is the hash of the path's NAR serialization, as an SRI hash
+	// (sha256-<base64>).
+	NarHash string `json:"narHash"`
+	NarSize int64  `json:"narSize"`
+}
+
+// StorePathInfos returns the metadata of the store paths that are in the local
+// store, keyed by path, with a single nix call. Paths that aren't in the local
+// store are left out.
+func StorePathInfos(storePaths ...string) (map[string]*PathInfo, error) {
+	present := []string{}
+	for _, storePath := range storePaths {
+		if _, err := os.Stat(storePath); err == nil {
+			present = append(present, storePath)
+		}
+	}
+	if len(present) == 0 {
+		return map[string]*PathInfo{}, nil
+	}
+	out, err := command(append([]string{"path-info", "--json"}, present...)...).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "nix path-info %s", strings.Join(present, " "))
+	}
+	return parsePathInfos(out)
+}
+
+// BinaryCachePathInfo returns the metadata of a store path in a binary cache,
+// like https://cache.nixos.org, without downloading the path. It returns false
+// if the cache doesn't have the path.
+func BinaryCachePathInfo(cacheURL, storePath string) (*PathInfo, bool, error) {
+	out, err := command("path-info", "--json", "--store", cacheURL, storePath).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && isPathNotValid(exitErr.Stderr) {
+		debug.Log("nix path-info %s in %s: %s", storePath, cacheURL, exitErr.Stderr)
+		return nil, false, nil
+	}
+	if errors.As(err, &exitErr) {
+		return nil, false, errors.Errorf(
+			"nix path-info %s in %s: %s", storePath, cacheURL, bytes.TrimSpace(exitErr.Stderr),
+		)
+	}
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "nix path-info %s", storePath)
+	}
+	return parsePathInfo(storePath, out)
+}
+
+// pathNotValidRegex matches the error that nix prints for a path that isn't in
+// a store, like "error: path '/nix/store/<hash>-go-1.20.5' is not valid".
+var pathNotValidRegex = regexp.MustCompile(`path '[^']*' is not valid`)
+
+// isPathNotValid returns true if nix failed because a path isn't in the store,
+// as opposed to failing to reach the store.
+func isPathNotValid(stderr []byte) bool {
+	return pathNotValidRegex.Match(stderr)
+}
+
+// CopyFromBinaryCache substitutes store paths, and their closures, from a
+// binary cache into the local store. Unlike installing a flake reference, it
+// doesn't evaluate anything. The keys of caches are used to check the
+// signatures of the paths.
+func CopyFromBinaryCache(
+	w io.Writer,
+	caches BinaryCaches,
+	cacheURL string,
+	storePaths ...string,
+) error {
+	cmd := command(append([]string{"copy", "--from", cacheURL}, storePaths...)...)
+	cmd.Args = append(cmd.Args, caches.Flags()...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return errors.Wrapf(cmd.Run(), "nix copy --from %s", cacheURL)
+}
+
+// CopyToBinaryCache uploads store paths, and their closures, from the local
+// store to a binary cache. Nix skips the paths that the cache already has.
+func CopyToBinaryCache(w io.Writer, cacheURL string, storePaths ...string) error {
+	cmd := command(append([]string{"copy", "--to", cacheURL}, storePaths...)...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return errors.Wrapf(cmd.Run(), "nix copy --to %s", cacheURL)
+}
+
+func parsePathInfo(storePath string, out []byte) (*PathInfo, bool, error) {
+	infos, err := parsePathInfos(out)
+	if err != nil {
+		return nil, false, err
+	}
+	info, ok := infos[storePath]
+	return info, ok, nil
+}
+
+// parsePathInfos parses the output of nix path-info --json, keyed by path.
+func parsePathInfos(out []byte) (map[string]*PathInfo, error) {
+	// Nix 2.19 and later print an object keyed by path. Older versions print
+	// an array.
+	infos := []*PathInfo{}
+	if err := json.Unmarshal(out, &infos); err != nil {
+		byPath := map[string]*PathInfo{}
+		if err := json.Unmarshal(out, &byPath); err != nil {
+			return nil, errors.Wrap(err, "failed to parse nix path-info output")
+		}
+		for path, info := range byPath {
+			if info != nil {
+				info.Path = path
+				infos = append(infos, info)
+			}
+		}
+	}
+	result := map[string]*PathInfo{}
+	for _, info := range infos {
+		// Nix prints paths that aren't valid without a hash.
+		if info == nil || info.NarHash == "" {
+			continue
+		}
+		narHash, err := SRIHash(info.NarHash)
+		if err != nil {
+			return nil, err
+		}
+		info.NarHash = narHash
+		result[info.Path] = info
+	}
+	return result, nil
+}
+
+// nixBase32Alphabet is the alphabet of nix's base32 encoding, which leaves out
+// e, o, t and u.
+const nixBase32Alphabet = "0123456789abcdfghijklmnpqrsvwxyz"
+
+// SRIHash converts a sha256 hash printed by nix, which may be an SRI hash
+// (sha256-<base64>), or sha256:<base32> or sha256:<base16>, to an SRI hash.
+func SRIHash(hash string) (string, error) {
+	if strings.HasPrefix(hash, "sha256-") {
+		return hash, nil
+	}
+	digest, found := strings.CutPrefix(hash, "sha256:")
+	if !found {
+		return "", errors.Errorf("unsupported hash %q, expected a sha256 hash", hash)
+	}
+
+	var sum []byte
+	switch len(digest) {
+	case 64:
+		var err error
+		if sum, err = hex.DecodeString(digest); err != nil {
+			return "", errors.Errorf("invalid base16 hash %q", hash)
+		}
+	case 52:
+		// Nix's base32 encodes the bytes in reverse order, 5 bits at a time.
+		sum = make([]byte, 32)
+		for n := 0; n < len(digest); n++ {
+			c := digest[len(digest)-n-1]
+			digit := strings.IndexByte(nixBase32Alphabet, c)
+			if digit < 0 {
+				return "", errors.Errorf("invalid base32 hash %q", hash)
+			}
+			b := n * 5
+			i, j := b/8, b%8
+			sum[i] |= byte(digit << j)
+			if i+1 < len(sum) {
+				sum[i+1] |= byte(digit >> (8 - j))
+			}
+		}
+	default:
+		return "", errors.Errorf("invalid sha256 hash %q", hash)
+	}
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum), nil
+}
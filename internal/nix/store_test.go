@@ -30,3 +30,83 @@ func TestContentAddressedPath(t *testing.T) {
 
 	}
 }
+
+func TestSRIHash(t *testing.T) {
+	// The sha256 of the empty string.
+	const want = "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	for _, hash := range []string{
+		want,
+		"sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"sha256:0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73",
+	} {
+		got, err := SRIHash(hash)
+		if err != nil {
+			t.Errorf("SRIHash(%q) got error: %v", hash, err)
+		} else if got != want {
+			t.Errorf("SRIHash(%q) = %s, want %s", hash, got, want)
+		}
+	}
+
+	for _, hash := range []string{"md5:d41d8cd98f00b204e9800998ecf8427e", "sha256:xyz"} {
+		if _, err := SRIHash(hash); err == nil {
+			t.Errorf("SRIHash(%q) got no error", hash)
+		}
+	}
+}
+
+func TestParsePathInfo(t *testing.T) {
+	const path = "/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5"
+	outputs := map[string]string{
+		"array":  `[{"path": "` + path + `", "narHash": "sha256:0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73", "narSize": 120}]`,
+		"object": `{"` + path + `": {"narHash": "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", "narSize": 120}}`,
+	}
+	for name, out := range outputs {
+		t.Run(name, func(t *testing.T) {
+			info, ok, err := parsePathInfo(path, []byte(out))
+			if err != nil || !ok {
+				t.Fatalf("got ok %v and error %v", ok, err)
+			}
+			if info.NarHash != "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" || info.NarSize != 120 {
+				t.Errorf("got %+v", info)
+			}
+		})
+	}
+}
+
+func TestParsePathInfos(t *testing.T) {
+	const (
+		valid   = "/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5"
+		invalid = "/nix/store/1m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.6"
+	)
+	outputs := map[string]string{
+		"array": `[{"path": "` + valid + `", "narHash": "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", "narSize": 120},` +
+			`{"path": "` + invalid + `", "valid": false}]`,
+		"object": `{"` + valid + `": {"narHash": "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", "narSize": 120},` +
+			`"` + invalid + `": null}`,
+	}
+	for name, out := range outputs {
+		t.Run(name, func(t *testing.T) {
+			infos, err := parsePathInfos([]byte(out))
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if len(infos) != 1 || infos[valid] == nil || infos[valid].NarSize != 120 {
+				t.Errorf("got %+v, want only the info of %s", infos, valid)
+			}
+		})
+	}
+}
+
+func TestIsPathNotValid(t *testing.T) {
+	tests := map[string]bool{
+		"error: path '/nix/store/0m4iqdvvkkdkfq2rx5j1k3s7i2h1mk6c-go-1.20.5' is not valid\n": true,
+		"error: unable to download 'https://cache.example.com/nix-cache-info': " +
+			"Couldn't resolve host name (6)\n": false,
+		"": false,
+	}
+	for stderr, want := range tests {
+		if got := isPathNotValid([]byte(stderr)); got != want {
+			t.Errorf("isPathNotValid(%q) = %v, want %v", stderr, got, want)
+		}
+	}
+}
@@ -12,12 +12,13 @@ import (
 	"go.jetpack.io/devbox/internal/ux"
 )
 
-func ProfileUpgrade(ProfileDir string, idx int) error {
+func ProfileUpgrade(ProfileDir string, idx int, caches BinaryCaches) error {
 	cmd := command(
 		"profile", "upgrade",
 		"--profile", ProfileDir,
 		fmt.Sprintf("%d", idx),
 	)
+	cmd.Args = append(cmd.Args, caches.Flags()...)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return redact.Errorf(
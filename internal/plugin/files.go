@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/plugins"
 )
 
@@ -26,6 +27,14 @@ func getConfigIfAny(pkg Includable, projectDir string) (*config, error) {
 		return buildConfig(pkg, projectDir, string(content))
 	}
 
+	if remote, ok := pkg.(*remotePlugin); ok {
+		content, err := remote.content()
+		if err != nil {
+			return nil, err
+		}
+		return buildConfig(pkg, projectDir, string(content))
+	}
+
 	for _, file := range configFiles {
 		if file.IsDir() || strings.HasSuffix(file.Name(), ".go") {
 			continue
@@ -56,5 +65,12 @@ func getFileContent(pkg Includable, contentPath string) ([]byte, error) {
 	if local, ok := pkg.(*localPlugin); ok {
 		return os.ReadFile(local.contentPath(contentPath))
 	}
+	if _, ok := pkg.(*remotePlugin); ok {
+		return nil, usererr.New(
+			"remote include %s cannot create files. Only local and built-in "+
+				"plugins support create_files",
+			pkg.CanonicalName(),
+		)
+	}
 	return plugins.BuiltIn.ReadFile(contentPath)
 }
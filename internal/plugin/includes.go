@@ -7,8 +7,10 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/devconfig"
 	"go.jetpack.io/devbox/internal/devpkg"
 )
 
@@ -18,6 +20,9 @@ type Includable interface {
 }
 
 func (m *Manager) ParseInclude(include string) (Includable, error) {
+	if IsRemoteInclude(include) {
+		return newRemotePlugin(include, m.lockfile, m.ProjectDir())
+	}
 	includeType, name, _ := strings.Cut(include, ":")
 	if name == "" {
 		return nil, usererr.New("include name is required")
@@ -30,6 +35,94 @@ func (m *Manager) ParseInclude(include string) (Includable, error) {
 	return nil, usererr.New("unknown include type %q", includeType)
 }
 
+// IsConfigInclude returns true if the include is a devbox config (a local or
+// remote file) whose packages, env, init hook and scripts are merged into the
+// including config. Built-in plugins are not merged.
+func IsConfigInclude(include string) bool {
+	return IsRemoteInclude(include) || strings.HasPrefix(include, "path:")
+}
+
+// IncludedConfigs loads the configs of the given includes, in order, skipping
+// built-in plugins. The configs are rendered with the same template variables
+// as plugins.
+func (m *Manager) IncludedConfigs(
+	includes []string,
+) ([]*devconfig.IncludedConfig, error) {
+	configs, _, err := m.includedConfigs(includes, false /*cachedOnly*/)
+	return configs, err
+}
+
+// CachedIncludedConfigs is like IncludedConfigs, but it doesn't access the
+// network or change the lockfile. It skips the remote includes that aren't
+// locked, or whose locked content isn't cached, and returns them.
+func (m *Manager) CachedIncludedConfigs(
+	includes []string,
+) ([]*devconfig.IncludedConfig, []string, error) {
+	return m.includedConfigs(includes, true /*cachedOnly*/)
+}
+
+func (m *Manager) includedConfigs(
+	includes []string,
+	cachedOnly bool,
+) ([]*devconfig.IncludedConfig, []string, error) {
+	result := []*devconfig.IncludedConfig{}
+	skipped := []string{}
+	for _, include := range includes {
+		if !IsConfigInclude(include) {
+			continue
+		}
+		pkg, err := m.ParseInclude(include)
+		if err != nil {
+			return nil, nil, err
+		}
+		var content []byte
+		if remote, ok := pkg.(*remotePlugin); ok && cachedOnly {
+			if content, ok = remote.cachedContent(); !ok {
+				skipped = append(skipped, include)
+				continue
+			}
+		} else if content, err = includeContent(pkg); err != nil {
+			return nil, nil, err
+		}
+		data, err := renderConfig(pkg, m.ProjectDir(), string(content))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := validateSchema(data, includeFilename(pkg)); err != nil {
+			return nil, nil, err
+		}
+		cfg, err := devconfig.LoadBytes(data, ".json")
+		if err != nil {
+			return nil, nil, usererr.WithUserMessage(err, "error loading include %s", include)
+		}
+		result = append(result, &devconfig.IncludedConfig{Config: cfg, Ref: include})
+	}
+	return result, skipped, nil
+}
+
+// includeContent returns the raw content of a local or remote include.
+func includeContent(pkg Includable) ([]byte, error) {
+	switch include := pkg.(type) {
+	case *localPlugin:
+		content, err := os.ReadFile(include.path)
+		return content, errors.WithStack(err)
+	case *remotePlugin:
+		return include.content()
+	}
+	return nil, errors.Errorf("%s is not a config include", pkg.CanonicalName())
+}
+
+// includeFilename returns the name used to report errors in an include.
+func includeFilename(pkg Includable) string {
+	switch include := pkg.(type) {
+	case *localPlugin:
+		return include.path
+	case *remotePlugin:
+		return include.ref
+	}
+	return pkg.CanonicalName()
+}
+
 type localPlugin struct {
 	name string
 	path string
@@ -48,10 +141,10 @@ func newLocalPlugin(path string) (*localPlugin, error) {
 	}
 	name, ok := m["name"].(string)
 	if !ok || name == "" {
-		return nil,
-			usererr.New("plugin %s is missing a required field 'name'", path)
-	}
-	if !nameRegex.MatchString(name) {
+		// Included devbox.json files don't have a name, so derive one from the
+		// path. It is used for the include's directories (e.g. its virtenv).
+		name = localIncludeName(path)
+	} else if !nameRegex.MatchString(name) {
 		return nil, usererr.New(
 			"plugin %s has an invalid name %q. Name must match %s",
 			path, name, nameRegex,
@@ -63,6 +156,17 @@ func newLocalPlugin(path string) (*localPlugin, error) {
 	}, nil
 }
 
+// localIncludeName returns a name for an included file that doesn't specify
+// one. For example, "services/api/devbox.json" is named "api" and
+// "configs/base.json" is named "base".
+func localIncludeName(path string) string {
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if stem == "devbox" {
+		stem = filepath.Base(filepath.Dir(path))
+	}
+	return sanitizeName(stem)
+}
+
 func (l *localPlugin) CanonicalName() string {
 	return l.name
 }
@@ -0,0 +1,54 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/lock"
+)
+
+type testProject struct {
+	dir string
+}
+
+func (p *testProject) Packages() []string { return nil }
+func (p *testProject) ProjectDir() string { return p.dir }
+
+func TestIncludedConfigs(t *testing.T) {
+	dir := t.TempDir()
+	base := `{
+  "packages": ["hello@latest"],
+  "substituters": ["https://cache.example.com"],
+  "trusted_public_keys": ["cache.example.com-1:abc="]
+}`
+	if err := os.WriteFile(filepath.Join(dir, "base.json"), []byte(base), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(
+		WithDevbox(&testProject{dir: dir}),
+		WithLockfile(&lock.File{Packages: map[string]*lock.Package{}}),
+	)
+
+	configs, err := manager.IncludedConfigs([]string{"plugin:nodejs", "path:base.json"})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("got %d configs, want only the config of path:base.json", len(configs))
+	}
+	if configs[0].Ref != "path:base.json" {
+		t.Errorf("got ref %q, want path:base.json", configs[0].Ref)
+	}
+	if diff := cmp.Diff([]string{"https://cache.example.com"}, configs[0].Substituters); diff != "" {
+		t.Errorf("wrong substituters (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"cache.example.com-1:abc="}, configs[0].TrustedPublicKeys); diff != "" {
+		t.Errorf("wrong trusted public keys (-want +got):\n%s", diff)
+	}
+}
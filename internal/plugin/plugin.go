@@ -128,6 +128,16 @@ func (m *Manager) create(pkg Includable, locked *lock.Package) error {
 	return m.lockfile.Save()
 }
 
+// PluginVersion returns the version of the built-in plugin of a package, or
+// false if the package doesn't have a plugin.
+func (m *Manager) PluginVersion(pkg *devpkg.Package) (string, bool, error) {
+	cfg, err := getConfigIfAny(pkg, m.ProjectDir())
+	if err != nil || cfg == nil {
+		return "", false, err
+	}
+	return cfg.Version, true, nil
+}
+
 func (m *Manager) createFile(
 	pkg Includable,
 	filePath, contentPath, virtenvPath string,
@@ -202,6 +212,10 @@ func (m *Manager) Env(
 		allPkgs = append(allPkgs, pkg)
 	}
 	for _, included := range includes {
+		// The env of included configs is part of the effective config.
+		if IsConfigInclude(included) {
+			continue
+		}
 		input, err := m.ParseInclude(included)
 		if err != nil {
 			return nil, err
@@ -227,6 +241,15 @@ func (m *Manager) Env(
 
 func buildConfig(pkg Includable, projectDir, content string) (*config, error) {
 	cfg := &config{}
+	data, err := renderConfig(pkg, projectDir, content)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, errors.WithStack(json.Unmarshal(data, cfg))
+}
+
+// renderConfig executes a plugin or included config as a template.
+func renderConfig(pkg Includable, projectDir, content string) ([]byte, error) {
 	name := pkg.CanonicalName()
 	t, err := template.New(name + "-template").Parse(content)
 	if err != nil {
@@ -242,8 +265,7 @@ func buildConfig(pkg Includable, projectDir, content string) (*config, error) {
 	}); err != nil {
 		return nil, errors.WithStack(err)
 	}
-
-	return cfg, errors.WithStack(json.Unmarshal(buf.Bytes(), cfg))
+	return buf.Bytes(), nil
 }
 
 func createDir(path string) error {
@@ -0,0 +1,214 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devconfig"
+	"go.jetpack.io/devbox/internal/lock"
+)
+
+const githubRawContentHost = "https://raw.githubusercontent.com"
+
+// remoteIncludesCachePath is where fetched remote includes are stored, keyed
+// by content hash. This allows shells to start offline once an include has
+// been locked.
+var remoteIncludesCachePath = filepath.Join(devboxHiddenDirName, "cache", "includes")
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_\-]+`)
+
+// remotePlugin is an include that is fetched over https, either from an
+// arbitrary URL (https://...) or from a github repository
+// (github:owner/repo[/ref][?dir=subdir]). Its content is pinned in the
+// lockfile by hash so that every user gets the same config.
+type remotePlugin struct {
+	// ref is the include as written in devbox.json
+	ref        string
+	name       string
+	url        string
+	lockfile   *lock.File
+	projectDir string
+
+	data []byte // memoized value from content()
+}
+
+// IsRemoteInclude returns true if the include is fetched over the network.
+func IsRemoteInclude(include string) bool {
+	return strings.HasPrefix(include, "https://") ||
+		strings.HasPrefix(include, "github:")
+}
+
+func newRemotePlugin(
+	ref string,
+	lockfile *lock.File,
+	projectDir string,
+) (*remotePlugin, error) {
+	plugin := &remotePlugin{
+		ref:        ref,
+		lockfile:   lockfile,
+		projectDir: projectDir,
+	}
+	var err error
+	if strings.HasPrefix(ref, "github:") {
+		plugin.url, plugin.name, err = parseGithubInclude(ref)
+	} else {
+		plugin.url, plugin.name, err = parseHTTPSInclude(ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// parseGithubInclude returns the raw content URL and a name for an include of
+// the form github:owner/repo[/ref][?dir=subdir]. The included file is the
+// devbox.json in the root of the repo, or in dir if specified. If ref is
+// omitted, master is used.
+func parseGithubInclude(ref string) (string, string, error) {
+	repoPath, query, _ := strings.Cut(strings.TrimPrefix(ref, "github:"), "?")
+	parts := strings.Split(repoPath, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", usererr.New(
+			"invalid github include %q. Must be of the form "+
+				"github:owner/repo[/ref][?dir=subdir]",
+			ref,
+		)
+	}
+	revision := "master"
+	if len(parts) == 3 && parts[2] != "" {
+		revision = parts[2]
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", "", usererr.WithUserMessage(err, "invalid github include %q", ref)
+	}
+	dir := values.Get("dir")
+
+	rawURL, err := url.JoinPath(
+		githubRawContentHost,
+		parts[0], parts[1], revision, dir, devconfig.DefaultName,
+	)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	name := strings.Join(lo.Compact([]string{parts[0], parts[1], dir}), "-")
+	return rawURL, sanitizeName(name), nil
+}
+
+func parseHTTPSInclude(ref string) (string, string, error) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Host == "" {
+		return "", "", usererr.New("invalid include url %q", ref)
+	}
+	name := u.Host + strings.TrimSuffix(u.Path, path.Ext(u.Path))
+	return ref, sanitizeName(name), nil
+}
+
+func (p *remotePlugin) CanonicalName() string {
+	return p.name
+}
+
+// Hash returns the hash of the include's content. If the content can't be
+// retrieved, it falls back to hashing the reference.
+func (p *remotePlugin) Hash() string {
+	if data, err := p.content(); err == nil {
+		return contentHash(data)
+	}
+	h, _ := cuecfg.Hash(p.ref)
+	return h
+}
+
+// content returns the include's content. If the include is locked and the
+// locked content is cached, no network request is made. Otherwise, the content
+// is fetched, checked against the locked hash (if any), cached and locked.
+func (p *remotePlugin) content() ([]byte, error) {
+	if p.data != nil {
+		return p.data, nil
+	}
+
+	if data, ok := p.cachedContent(); ok {
+		p.data = data
+		return data, nil
+	}
+
+	locked := p.lockfile.Packages[p.ref]
+
+	data, err := devconfig.FetchURL(p.url)
+	if err != nil {
+		return nil, usererr.WithUserMessage(err, "failed to fetch include %s", p.ref)
+	}
+	hash := contentHash(data)
+	if locked != nil && locked.ContentHash != "" && locked.ContentHash != hash {
+		return nil, usererr.New(
+			"The content of include %s has changed since it was locked in "+
+				"devbox.lock. Run `devbox update` to use the new content.",
+			p.ref,
+		)
+	}
+	if err := validateSchema(data, p.ref); err != nil {
+		return nil, err
+	}
+	if _, err := devconfig.LoadBytes(data, ".json"); err != nil {
+		return nil, usererr.WithUserMessage(err, "include %s is not a valid devbox config", p.ref)
+	}
+
+	cachePath := p.cachePath(hash)
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if locked == nil {
+		locked = &lock.Package{}
+		p.lockfile.Packages[p.ref] = locked
+	}
+	locked.Resolved = p.url
+	locked.ContentHash = hash
+
+	p.data = data
+	return data, nil
+}
+
+// cachedContent returns the locked content of the include from the cache, and
+// false if the include isn't locked or its content isn't cached.
+func (p *remotePlugin) cachedContent() ([]byte, bool) {
+	locked := p.lockfile.Packages[p.ref]
+	if locked == nil || locked.ContentHash == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(p.cachePath(locked.ContentHash))
+	if err != nil || contentHash(data) != locked.ContentHash {
+		debug.Log("cache miss for include %s: %v", p.ref, err)
+		return nil, false
+	}
+	return data, true
+}
+
+func (p *remotePlugin) cachePath(hash string) string {
+	return filepath.Join(p.projectDir, remoteIncludesCachePath, hash+".json")
+}
+
+func contentHash(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func sanitizeName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-")
+}
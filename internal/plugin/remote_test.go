@@ -0,0 +1,150 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.jetpack.io/devbox/internal/lock"
+)
+
+func TestParseGithubInclude(t *testing.T) {
+	cases := []struct {
+		ref     string
+		url     string
+		name    string
+		wantErr bool
+	}{
+		{
+			ref:  "github:acme/base",
+			url:  "https://raw.githubusercontent.com/acme/base/master/devbox.json",
+			name: "acme-base",
+		},
+		{
+			ref:  "github:acme/base/v1.2.0",
+			url:  "https://raw.githubusercontent.com/acme/base/v1.2.0/devbox.json",
+			name: "acme-base",
+		},
+		{
+			ref:  "github:acme/monorepo/main?dir=configs/go",
+			url:  "https://raw.githubusercontent.com/acme/monorepo/main/configs/go/devbox.json",
+			name: "acme-monorepo-configs-go",
+		},
+		{ref: "github:acme", wantErr: true},
+		{ref: "github:acme/base/main/extra", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.ref, func(t *testing.T) {
+			url, name, err := parseGithubInclude(tc.ref)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got nil error for %q, want error", tc.ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if url != tc.url {
+				t.Errorf("got url %q, want %q", url, tc.url)
+			}
+			if name != tc.name {
+				t.Errorf("got name %q, want %q", name, tc.name)
+			}
+		})
+	}
+}
+
+func TestRemotePluginLocksAndCachesContent(t *testing.T) {
+	body := `{"packages": ["go@1.20"], "env": {"FOO": "bar"}}`
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			_, _ = w.Write([]byte(body))
+		},
+	))
+	defer server.Close()
+
+	// httptest servers are plain http, so build the plugin directly instead of
+	// through newRemotePlugin which only accepts https.
+	lockfile := &lock.File{Packages: map[string]*lock.Package{}}
+	ref := server.URL + "/base.json"
+	newPlugin := func(projectDir string) *remotePlugin {
+		return &remotePlugin{
+			ref:        ref,
+			name:       "base",
+			url:        ref,
+			lockfile:   lockfile,
+			projectDir: projectDir,
+		}
+	}
+
+	projectDir := t.TempDir()
+	if _, err := newPlugin(projectDir).content(); err != nil {
+		t.Fatalf("got error fetching content: %v", err)
+	}
+	locked := lockfile.Packages[ref]
+	if locked == nil || locked.ContentHash != contentHash([]byte(body)) {
+		t.Fatalf("include was not locked: %+v", locked)
+	}
+
+	// A new plugin in the same project should be served from the cache.
+	if _, err := newPlugin(projectDir).content(); err != nil {
+		t.Fatalf("got error reading cached content: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+
+	// Without a cache, content that no longer matches the lock is rejected.
+	body = `{"packages": ["go@1.21"]}`
+	if _, err := newPlugin(t.TempDir()).content(); err == nil {
+		t.Error("got nil error for content that changed upstream, want error")
+	}
+}
+
+func TestRemotePluginCachedContent(t *testing.T) {
+	body := `{"packages": ["go@1.20"]}`
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		},
+	))
+	defer server.Close()
+
+	lockfile := &lock.File{Packages: map[string]*lock.Package{}}
+	ref := server.URL + "/base.json"
+	projectDir := t.TempDir()
+	plugin := &remotePlugin{
+		ref:        ref,
+		name:       "base",
+		url:        ref,
+		lockfile:   lockfile,
+		projectDir: projectDir,
+	}
+
+	if _, ok := plugin.cachedContent(); ok {
+		t.Fatal("got cached content for an include that isn't locked")
+	}
+	if len(lockfile.Packages) != 0 {
+		t.Fatalf("got lockfile entries %v, want cachedContent to not lock the include", lockfile.Packages)
+	}
+
+	if _, err := plugin.content(); err != nil {
+		t.Fatalf("got error fetching content: %v", err)
+	}
+	data, ok := plugin.cachedContent()
+	if !ok || string(data) != body {
+		t.Errorf("got cached content %q, %v, want %q, true", data, ok, body)
+	}
+
+	plugin.projectDir = t.TempDir()
+	if _, ok := plugin.cachedContent(); ok {
+		t.Error("got cached content in a project without a cache")
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package plugin
+
+import (
+	_ "embed"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/cuecfg"
+)
+
+// Schema is the JSON Schema of plugins and included configs. It accepts every
+// field of devbox.json (see devconfig.Schema) plus the fields of config. The
+// version in the file name must be bumped on backwards incompatible changes.
+// TestSchemaAcceptsDevboxConfig checks that it keeps accepting devbox.json.
+//
+//go:embed schema/plugin.v1.schema.json
+var Schema []byte
+
+// validateSchema validates the content of a plugin or included config against
+// Schema. Built-in plugins are validated by tests instead.
+func validateSchema(data []byte, filename string) error {
+	if err := cuecfg.ValidateJSONSchema(Schema, data, filename); err != nil {
+		return usererr.New("%s", err)
+	}
+	return nil
+}
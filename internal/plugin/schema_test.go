@@ -0,0 +1,88 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package plugin
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/devconfig"
+	"go.jetpack.io/devbox/plugins"
+)
+
+// TestBuiltInPluginsMatchSchema validates built-in plugins, which are not
+// validated at runtime.
+func TestBuiltInPluginsMatchSchema(t *testing.T) {
+	files, err := plugins.BuiltIn.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		t.Run(file.Name(), func(t *testing.T) {
+			content, err := plugins.BuiltIn.ReadFile(file.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg := &localPlugin{name: strings.TrimSuffix(file.Name(), ".json")}
+			data, err := renderConfig(pkg, t.TempDir(), string(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := validateSchema(data, file.Name()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaRejectsUnknownKeys(t *testing.T) {
+	content := `{
+  "name": "my-plugin",
+  "create_file": {}
+}`
+	err := validateSchema([]byte(content), "my-plugin.json")
+	if err == nil {
+		t.Fatal("got nil error for unknown key, want error")
+	}
+	want := "my-plugin.json:3:3: field not allowed: create_file"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, want it to contain %q", err, want)
+	}
+}
+
+// TestSchemaAcceptsDevboxConfig checks that Schema accepts every field of
+// devbox.json, with the same definitions as devconfig.Schema.
+func TestSchemaAcceptsDevboxConfig(t *testing.T) {
+	var devboxSchema, pluginSchema struct {
+		Definitions map[string]map[string]any `json:"definitions"`
+	}
+	if err := json.Unmarshal(devconfig.Schema, &devboxSchema); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(Schema, &pluginSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	pluginFields := pluginSchema.Definitions["plugin"]["properties"].(map[string]any)
+	for field := range devboxSchema.Definitions["config"]["properties"].(map[string]any) {
+		if _, ok := pluginFields[field]; !ok {
+			t.Errorf("plugin schema doesn't accept the devbox.json field %q", field)
+		}
+	}
+	for name, def := range devboxSchema.Definitions {
+		if name == "config" {
+			continue
+		}
+		if diff := cmp.Diff(def, pluginSchema.Definitions[name]); diff != "" {
+			t.Errorf("definition %q differs from devbox.json's (-devbox +plugin):\n%s", name, diff)
+		}
+	}
+}
@@ -7,13 +7,20 @@ import (
 	"fmt"
 	"os"
 
+	"golang.org/x/exp/maps"
+
+	"go.jetpack.io/devbox/internal/devconfig"
 	"go.jetpack.io/devbox/internal/devpkg"
 	"go.jetpack.io/devbox/internal/services"
 )
 
+// GetServices returns the services of the plugins of pkgs and includes, and
+// the services declared in devbox.json, which override plugin services with
+// the same name.
 func (m *Manager) GetServices(
 	pkgs []*devpkg.Package,
 	includes []string,
+	configServices map[string]*devconfig.ServiceConfig,
 ) (services.Services, error) {
 	allSvcs := services.Services{}
 
@@ -48,5 +55,11 @@ func (m *Manager) GetServices(
 		}
 	}
 
+	configSvcs, err := services.FromConfig(m.ProjectDir(), configServices)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(allSvcs, configSvcs)
+
 	return allSvcs, nil
 }
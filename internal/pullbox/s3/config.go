@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/auth"
@@ -48,3 +49,23 @@ func assumeRole(ctx context.Context, user *auth.User) (*aws.Config, error) {
 	}
 	return &config, err
 }
+
+// NewClient returns an S3 client that uses the default AWS credentials of the
+// user, like the AWS CLI does, instead of the Jetpack cloud role. Region and
+// endpoint are optional; endpoint is for S3-compatible stores like MinIO.
+func NewClient(ctx context.Context, region, endpoint string) (*s3.Client, error) {
+	opts := []func(*config.LoadOptions) error{}
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package searcher
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/nix"
+)
+
+// IndexNixpkgs returns the packages of a nixpkgs git checkout for the given
+// systems, as versions that can be added to an Index. Packages are named by
+// their pname, so attributes like go_1_20 and go become versions of "go".
+func IndexNixpkgs(dir string, systems []string) ([]PackageVersion, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	commit, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := gitOutput(dir, "log", "-1", "--format=%ct")
+	if err != nil {
+		return nil, err
+	}
+	lastUpdated, err := strconv.Atoi(timestamp)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	type key struct{ name, version string }
+	versions := map[key]*PackageVersion{}
+	for _, system := range systems {
+		infos, err := nix.SearchAll("path:"+dir, system)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if info.PName == "" || info.Version == "" {
+				continue
+			}
+			k := key{info.PName, info.Version}
+			if versions[k] == nil {
+				versions[k] = &PackageVersion{
+					Name:    info.PName,
+					Systems: map[string]PackageInfo{},
+				}
+			}
+			pi := versions[k].Systems[system]
+			pi.CommitHash = commit
+			pi.System = system
+			pi.LastUpdated = lastUpdated
+			pi.MetaName = info.PName
+			pi.Version = info.Version
+			pi.AttrPaths = append(pi.AttrPaths, attrPath(info.AttributeKey, system))
+			versions[k].Systems[system] = pi
+		}
+	}
+
+	result := []PackageVersion{}
+	for _, v := range versions {
+		for system, pi := range v.Systems {
+			// Prefer the shortest attribute path, which is usually the
+			// canonical one (e.g. "go" over "go_1_20").
+			slices.SortFunc(pi.AttrPaths, func(a, b string) bool {
+				return len(a) < len(b) || (len(a) == len(b) && a < b)
+			})
+			v.Systems[system] = pi
+		}
+		systemNames := maps.Keys(v.Systems)
+		slices.Sort(systemNames)
+		v.PackageInfo = v.Systems[systemNames[0]]
+		result = append(result, *v)
+	}
+	return result, nil
+}
+
+// attrPath strips the "legacyPackages.<system>." prefix of the attribute keys
+// returned by nix search.
+func attrPath(attributeKey, system string) string {
+	return strings.TrimPrefix(attributeKey, "legacyPackages."+system+".")
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s is not a nixpkgs git checkout", dir)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -4,25 +4,108 @@
 package searcher
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/semver"
+	"go.jetpack.io/devbox/internal/ux"
+	"go.jetpack.io/devbox/internal/xdg"
 )
 
 const searchAPIEndpoint = "https://search.devbox.sh"
 
+const (
+	// requestTimeout is the timeout of each attempt of a request, including
+	// reading the response.
+	requestTimeout = 10 * time.Second
+	// maxAttempts is how many times a request is sent before giving up on
+	// network errors and server errors.
+	maxAttempts = 3
+	// initialBackoff is the delay before the first retry. It doubles after
+	// each retry.
+	initialBackoff = 250 * time.Millisecond
+	// cacheTTL is how long successful responses are cached on disk.
+	cacheTTL = time.Hour
+)
+
+var (
+	// ErrNotFound is returned when the search service has no package or version
+	// that matches a request.
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnavailable is returned when the search service can't be reached,
+	// fails to handle a request, or returns an invalid response. The offline
+	// index is used instead when it happens.
+	ErrUnavailable = errors.New("search service is unavailable")
+)
+
+// ResponseError is returned when the search service responds with an error
+// status. It matches ErrNotFound for 404 responses and ErrUnavailable for
+// server errors.
+type ResponseError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.URL, e.Status)
+}
+
+func (e *ResponseError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnavailable:
+		return e.temporary()
+	default:
+		return false
+	}
+}
+
+// temporary returns true if the request may succeed when it is retried.
+func (e *ResponseError) temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError ||
+		e.StatusCode == http.StatusTooManyRequests
+}
+
+// fallbackWarning is only shown once per command.
+var fallbackWarning sync.Once
+
 type client struct {
-	host string
+	host      string
+	indexPath string
+	// offline forces the client to use the offline index.
+	offline bool
+
+	http     *http.Client
+	backoff  time.Duration
+	cacheDir string
+	cacheTTL time.Duration
 }
 
 func Client() *client {
 	return &client{
-		host: envir.GetValueOrDefault(envir.DevboxSearchHost, searchAPIEndpoint),
+		host:      envir.GetValueOrDefault(envir.DevboxSearchHost, searchAPIEndpoint),
+		indexPath: IndexPath(),
+		offline:   forcedOffline(),
+		http:      &http.Client{Timeout: requestTimeout},
+		backoff:   initialBackoff,
+		cacheDir:  xdg.CacheSubpath(filepath.FromSlash("devbox/search")),
+		cacheTTL:  cacheTTL,
 	}
 }
 
@@ -30,6 +113,13 @@ func (c *client) Search(query string) (*SearchResults, error) {
 	if query == "" {
 		return nil, fmt.Errorf("query should not be empty")
 	}
+	if c.offline {
+		index, err := LoadIndex(c.indexPath)
+		if err != nil {
+			return nil, err
+		}
+		return index.Search(query), nil
+	}
 
 	endpoint, err := url.JoinPath(c.host, "v1/search")
 	if err != nil {
@@ -37,15 +127,30 @@ func (c *client) Search(query string) (*SearchResults, error) {
 	}
 	searchURL := endpoint + "?q=" + url.QueryEscape(query)
 
-	return execGet[SearchResults](searchURL)
+	results, err := execGet[SearchResults](c, searchURL)
+	if index := c.fallbackIndex(err); index != nil {
+		return index.Search(query), nil
+	}
+	return results, err
 }
 
 // Resolve calls the /resolve endpoint of the search service. This returns
 // the latest version of the package that matches the version constraint.
+// Version ranges like "^1.20" are resolved with resolveRange.
 func (c *client) Resolve(name, version string) (*PackageVersion, error) {
 	if name == "" || version == "" {
 		return nil, fmt.Errorf("name and version should not be empty")
 	}
+	if c.offline {
+		index, err := LoadIndex(c.indexPath)
+		if err != nil {
+			return nil, err
+		}
+		return index.Resolve(name, version)
+	}
+	if semver.IsRange(version) {
+		return c.resolveRange(name, version)
+	}
 
 	endpoint, err := url.JoinPath(c.host, "v1/resolve")
 	if err != nil {
@@ -55,19 +160,156 @@ func (c *client) Resolve(name, version string) (*PackageVersion, error) {
 		"?name=" + url.QueryEscape(name) +
 		"&version=" + url.QueryEscape(version)
 
-	return execGet[PackageVersion](searchURL)
+	pkgVersion, err := execGet[PackageVersion](c, searchURL)
+	if index := c.fallbackIndex(err); index != nil {
+		return index.Resolve(name, version)
+	}
+	return pkgVersion, err
 }
 
-func execGet[T any](url string) (*T, error) {
-	response, err := http.Get(url)
+// resolveRange resolves a version range, which the /resolve endpoint doesn't
+// support. It picks the newest version in the range among the versions that
+// search returns for the package, and resolves that version.
+func (c *client) resolveRange(name, version string) (*PackageVersion, error) {
+	constraint, err := semver.ParseConstraint(version)
+	if err != nil {
+		return nil, usererr.New("%v", err)
+	}
+	results, err := c.Search(name)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
-	data, err := io.ReadAll(response.Body)
+	for _, pkg := range results.Packages {
+		if pkg.Name != name {
+			continue
+		}
+		versions := []string{}
+		for _, v := range pkg.Versions {
+			versions = append(versions, v.Version)
+		}
+		if best, ok := constraint.Best(versions); ok {
+			return c.Resolve(name, best)
+		}
+	}
+	return nil, errors.Wrapf(ErrNotFound, "no version of %s matches %s", name, version)
+}
+
+// fallbackIndex returns the offline index if err means that the search
+// service is unavailable and the index exists. Otherwise, it returns nil.
+func (c *client) fallbackIndex(err error) *Index {
+	if !errors.Is(err, ErrUnavailable) {
+		return nil
+	}
+	index, indexErr := LoadIndex(c.indexPath)
+	if indexErr != nil {
+		debug.Log("failed to load offline package index: %v", indexErr)
+		return nil
+	}
+	debug.Log("using offline package index: %v", err)
+	fallbackWarning.Do(func() {
+		ux.Fwarning(
+			os.Stderr,
+			"%s is unavailable. Using the offline package index, updated on %s.\n",
+			c.host,
+			index.Updated.Format("2006-01-02"),
+		)
+	})
+	return index
+}
+
+// execGet returns the decoded response of a GET request, from the cache if a
+// recent enough response is cached. Network errors and server errors are
+// retried with exponential backoff.
+func execGet[T any](c *client, url string) (*T, error) {
+	cachePath := c.cachePath(url)
+	if data, ok := c.readCache(cachePath); ok {
+		var result T
+		if err := json.Unmarshal(data, &result); err == nil {
+			debug.Log("search: using cached response for %s", url)
+			return &result, nil
+		}
+	}
+
+	var data []byte
+	var err error
+	backoff := c.backoff
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		data, err = c.get(url)
+		var respErr *ResponseError
+		retry := !errors.As(err, &respErr) || respErr.temporary()
+		if err == nil || !retry || attempt == maxAttempts {
+			break
+		}
+		debug.Log("search: attempt %d of GET %s failed, retrying in %s: %v", attempt, url, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
 	if err != nil {
 		return nil, err
 	}
+
 	var result T
-	return &result, json.Unmarshal(data, &result)
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, errors.Wrapf(ErrUnavailable, "invalid response from GET %s: %v", url, err)
+	}
+	c.writeCache(cachePath, data)
+	return &result, nil
+}
+
+// get sends one GET request and returns the body of a successful response.
+func (c *client) get(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	response, err := c.http.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(ErrUnavailable, err.Error())
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, &ResponseError{
+			URL:        url,
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+		}
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(ErrUnavailable, err.Error())
+	}
+	return data, nil
+}
+
+func (c *client) cachePath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.cacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// readCache returns the cached response at path if it is more recent than the
+// cache's TTL.
+func (c *client) readCache(path string) ([]byte, bool) {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.cacheTTL {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// writeCache saves a response. Failing to save it isn't an error, since the
+// cache is only an optimization.
+func (c *client) writeCache(path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		debug.Log("search: failed to create cache directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		debug.Log("search: failed to cache response: %v", err)
+	}
 }
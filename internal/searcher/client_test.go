@@ -0,0 +1,179 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package searcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.jetpack.io/devbox/internal/envir"
+)
+
+// testClient returns a client of a search service stand-in that responds with
+// the given statuses, in order, and then with body.
+func testClient(t *testing.T, body string, statuses ...int) (*client, *atomic.Int32) {
+	t.Helper()
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv(envir.DevboxSearchHost, server.URL)
+	t.Setenv(envir.DevboxSearchIndex, filepath.Join(t.TempDir(), "no-index"))
+	c := Client()
+	c.backoff = time.Millisecond
+	c.cacheDir = t.TempDir()
+	return c, requests
+}
+
+const goVersion = `{"name": "go", "version": "1.20.5", "systems": {}}`
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	c, requests := testClient(t, goVersion, http.StatusBadGateway, http.StatusServiceUnavailable)
+	got, err := c.Resolve("go", "1.20")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got.Version != "1.20.5" {
+		t.Errorf("got version %s, want 1.20.5", got.Version)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+}
+
+func TestClientGivesUpOnServerErrors(t *testing.T) {
+	c, requests := testClient(t, goVersion,
+		http.StatusInternalServerError,
+		http.StatusInternalServerError,
+		http.StatusInternalServerError,
+	)
+	_, err := c.Resolve("go", "1.20")
+	if !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want ErrUnavailable", err)
+	}
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("got error %v, want a ResponseError with status 500", err)
+	}
+	if n := requests.Load(); n != maxAttempts {
+		t.Errorf("got %d requests, want %d", n, maxAttempts)
+	}
+}
+
+func TestClientNotFound(t *testing.T) {
+	c, requests := testClient(t, goVersion, http.StatusNotFound)
+	_, err := c.Resolve("go", "0.1")
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnavailable) {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("got %d requests, want 1 since 404 isn't retried", n)
+	}
+}
+
+func TestClientInvalidResponse(t *testing.T) {
+	c, _ := testClient(t, "<html>Bad Gateway</html>")
+	if _, err := c.Search("go"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("got error %v, want ErrUnavailable", err)
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	c, requests := testClient(t, goVersion)
+	for i := 0; i < 2; i++ {
+		if _, err := c.Resolve("go", "1.20"); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("got %d requests, want 1 since the response is cached", n)
+	}
+
+	// Other queries aren't cached.
+	if _, err := c.Resolve("go", "1.21"); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+
+	// Expired responses aren't used.
+	entries, err := os.ReadDir(c.cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := time.Now().Add(-c.cacheTTL - time.Minute)
+	for _, entry := range entries {
+		if err := os.Chtimes(filepath.Join(c.cacheDir, entry.Name()), expired, expired); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Resolve("go", "1.20"); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("got %d requests, want 3 since the cached response expired", n)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	c, _ := testClient(t, goVersion)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	c.host = server.URL
+	c.http.Timeout = 10 * time.Millisecond
+
+	if _, err := c.Search("go"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("got error %v, want ErrUnavailable", err)
+	}
+}
+
+func TestClientResolveRange(t *testing.T) {
+	resolved := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/search":
+			_, _ = w.Write([]byte(`{"packages": [
+				{"name": "go", "versions": [{"version": "1.21.0"}, {"version": "1.20.7"}, {"version": "1.19.12"}]},
+				{"name": "gopls", "versions": [{"version": "1.20.9"}]}
+			]}`))
+		case "/v1/resolve":
+			resolved = r.URL.Query().Get("version")
+			_, _ = w.Write([]byte(`{"name": "go", "version": "` + resolved + `"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv(envir.DevboxSearchHost, server.URL)
+	c := Client()
+	c.cacheDir = t.TempDir()
+
+	got, err := c.Resolve("go", ">=1.19 <1.21")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if resolved != "1.20.7" || got.Version != "1.20.7" {
+		t.Errorf("resolved %q to version %s, want 1.20.7", resolved, got.Version)
+	}
+
+	if _, err := c.Resolve("go", "^2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package searcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/semver"
+	"go.jetpack.io/devbox/internal/xdg"
+)
+
+// indexFormatVersion is incremented when the format of the index changes in a
+// way that older versions of devbox can't read.
+const indexFormatVersion = 1
+
+// Index is a local copy of the package data of the search service, which
+// devbox uses to search and resolve packages offline. It is stored as gzipped
+// JSON, and can be downloaded as a snapshot or built from nixpkgs checkouts.
+type Index struct {
+	FormatVersion int       `json:"format_version"`
+	Updated       time.Time `json:"updated"`
+	// Packages are sorted by name, and their versions from newest to oldest.
+	Packages []Package `json:"packages"`
+}
+
+// ErrNoIndex is returned when the offline index doesn't exist.
+var ErrNoIndex = usererr.New(
+	"the offline package index doesn't exist. Run `devbox search index pull <url>` " +
+		"or `devbox search index build <nixpkgs>` to create it")
+
+// IndexPath returns the path of the offline index. It can be changed with
+// DEVBOX_SEARCH_INDEX.
+func IndexPath() string {
+	return envir.GetValueOrDefault(
+		envir.DevboxSearchIndex,
+		xdg.DataSubpath(filepath.FromSlash("devbox/search-index.json.gz")),
+	)
+}
+
+// LoadIndex reads the index at path. It returns ErrNoIndex if it doesn't
+// exist.
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoIndex
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseIndex(data)
+}
+
+// FetchIndex reads an index snapshot from an http(s) URL or a file.
+func FetchIndex(src string) (*Index, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return ParseIndex(data)
+	}
+
+	response, err := http.Get(src)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, usererr.New("failed to download package index from %s: %s", src, response.Status)
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseIndex(data)
+}
+
+// ParseIndex parses an index, which may be gzipped.
+func ParseIndex(data []byte) (*Index, error) {
+	if gz, err := gzip.NewReader(bytes.NewReader(data)); err == nil {
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, errors.Wrap(err, "failed to decompress package index")
+		}
+	}
+	index := &Index{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, errors.Wrap(err, "failed to parse package index")
+	}
+	if index.FormatVersion > indexFormatVersion {
+		return nil, usererr.New(
+			"the package index has format version %d, which this version of "+
+				"devbox doesn't support. Please update devbox",
+			index.FormatVersion,
+		)
+	}
+	// Snapshots built by other tools may not be sorted.
+	index.sort()
+	return index, nil
+}
+
+// Save writes the index to path as gzipped JSON.
+func (i *Index) Save(path string) error {
+	i.FormatVersion = indexFormatVersion
+	data, err := json.Marshal(i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write(data); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := gz.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	// Write to a temporary file first so that readers never see a partial
+	// index.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp, path))
+}
+
+// Search returns the packages whose name contains query. Exact matches come
+// first, followed by names that start with query.
+func (i *Index) Search(query string) *SearchResults {
+	query = strings.ToLower(query)
+	rank := func(name string) int {
+		name = strings.ToLower(name)
+		switch {
+		case name == query:
+			return 0
+		case strings.HasPrefix(name, query):
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	results := &SearchResults{}
+	for _, pkg := range i.Packages {
+		if strings.Contains(strings.ToLower(pkg.Name), query) {
+			results.Packages = append(results.Packages, pkg)
+		}
+	}
+	slices.SortStableFunc(results.Packages, func(a, b Package) bool {
+		return rank(a.Name) < rank(b.Name)
+	})
+	results.NumResults = len(results.Packages)
+	return results
+}
+
+// Resolve returns the newest version of the package that meets the version
+// constraint, which can be "latest", a version prefix like "1.20", or a range
+// like "^1.20" (see semver.Constraint).
+func (i *Index) Resolve(name, version string) (*PackageVersion, error) {
+	pkg := i.find(name)
+	if pkg == nil {
+		return nil, errors.Wrapf(ErrNotFound, "package %s is not in the offline package index", name)
+	}
+	constraint, err := semver.ParseConstraint(version)
+	if err != nil {
+		return nil, usererr.New("%v", err)
+	}
+
+	var best *PackageVersion
+	for idx := range pkg.Versions {
+		v := &pkg.Versions[idx]
+		if constraint.Check(v.Version) && (best == nil || semver.Compare(v.Version, best.Version) > 0) {
+			best = v
+		}
+	}
+	if best == nil {
+		return nil, errors.Wrapf(
+			ErrNotFound, "no version of %s in the offline package index matches %s", name, version)
+	}
+	return best, nil
+}
+
+// Add adds versions of packages to the index. Versions that are already in the
+// index gain the systems of the added version. For each system, the data from
+// the most recently updated commit is kept.
+func (i *Index) Add(versions []PackageVersion) {
+	byName := map[string]int{}
+	for idx, pkg := range i.Packages {
+		byName[pkg.Name] = idx
+	}
+	for _, v := range versions {
+		if _, ok := byName[v.Name]; !ok {
+			byName[v.Name] = len(i.Packages)
+			i.Packages = append(i.Packages, Package{Name: v.Name})
+		}
+		pkg := &i.Packages[byName[v.Name]]
+
+		idx := slices.IndexFunc(pkg.Versions, func(existing PackageVersion) bool {
+			return existing.Version == v.Version
+		})
+		if idx < 0 {
+			pkg.Versions = append(pkg.Versions, v)
+			continue
+		}
+		existing := &pkg.Versions[idx]
+		if existing.Systems == nil {
+			existing.Systems = map[string]PackageInfo{}
+		}
+		for system, info := range v.Systems {
+			if current, ok := existing.Systems[system]; !ok || current.LastUpdated < info.LastUpdated {
+				existing.Systems[system] = info
+			}
+		}
+		if existing.LastUpdated < v.LastUpdated {
+			existing.PackageInfo = v.PackageInfo
+		}
+	}
+	i.sort()
+}
+
+func (i *Index) find(name string) *Package {
+	idx, found := slices.BinarySearchFunc(i.Packages, name, func(pkg Package, name string) int {
+		return strings.Compare(pkg.Name, name)
+	})
+	if !found {
+		return nil
+	}
+	return &i.Packages[idx]
+}
+
+func (i *Index) sort() {
+	slices.SortFunc(i.Packages, func(a, b Package) bool { return a.Name < b.Name })
+	for idx := range i.Packages {
+		pkg := &i.Packages[idx]
+		slices.SortStableFunc(pkg.Versions, func(a, b PackageVersion) bool {
+			return semver.Compare(a.Version, b.Version) > 0
+		})
+		pkg.NumVersions = len(pkg.Versions)
+	}
+}
+
+// Systems returns the systems that have packages in the index.
+func (i *Index) Systems() []string {
+	systems := map[string]bool{}
+	for _, pkg := range i.Packages {
+		for _, v := range pkg.Versions {
+			for system := range v.Systems {
+				systems[system] = true
+			}
+		}
+	}
+	result := maps.Keys(systems)
+	slices.Sort(result)
+	return result
+}
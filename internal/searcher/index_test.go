@@ -0,0 +1,207 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package searcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/envir"
+)
+
+func testVersion(name, version, system string, lastUpdated int) PackageVersion {
+	info := PackageInfo{
+		CommitHash:  "commit-" + version,
+		System:      system,
+		LastUpdated: lastUpdated,
+		Version:     version,
+		AttrPaths:   []string{name},
+	}
+	return PackageVersion{
+		PackageInfo: info,
+		Name:        name,
+		Systems:     map[string]PackageInfo{system: info},
+	}
+}
+
+func testIndex() *Index {
+	index := &Index{}
+	index.Add([]PackageVersion{
+		testVersion("go", "1.20.5", "x86_64-linux", 1),
+		testVersion("go", "1.21.0", "x86_64-linux", 2),
+		testVersion("go", "1.9.7", "x86_64-linux", 0),
+		testVersion("gopls", "0.12.4", "x86_64-linux", 2),
+		testVersion("cargo-go", "1.0", "x86_64-linux", 2),
+		testVersion("go", "1.20.5", "aarch64-darwin", 3),
+	})
+	return index
+}
+
+func TestIndexAdd(t *testing.T) {
+	index := testIndex()
+
+	names := []string{}
+	for _, pkg := range index.Packages {
+		names = append(names, pkg.Name)
+	}
+	if diff := cmp.Diff([]string{"cargo-go", "go", "gopls"}, names); diff != "" {
+		t.Errorf("wrong packages (-want +got):\n%s", diff)
+	}
+
+	goPkg := index.find("go")
+	versions := []string{}
+	for _, v := range goPkg.Versions {
+		versions = append(versions, v.Version)
+	}
+	if diff := cmp.Diff([]string{"1.21.0", "1.20.5", "1.9.7"}, versions); diff != "" {
+		t.Errorf("wrong versions, want newest first (-want +got):\n%s", diff)
+	}
+	if goPkg.NumVersions != 3 {
+		t.Errorf("got NumVersions %d, want 3", goPkg.NumVersions)
+	}
+	if got := len(goPkg.Versions[1].Systems); got != 2 {
+		t.Errorf("got %d systems for go 1.20.5, want 2", got)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	results := testIndex().Search("GO")
+	names := []string{}
+	for _, pkg := range results.Packages {
+		names = append(names, pkg.Name)
+	}
+	if diff := cmp.Diff([]string{"go", "gopls", "cargo-go"}, names); diff != "" {
+		t.Errorf("wrong search results (-want +got):\n%s", diff)
+	}
+	if results.NumResults != 3 {
+		t.Errorf("got NumResults %d, want 3", results.NumResults)
+	}
+}
+
+func TestIndexResolve(t *testing.T) {
+	index := testIndex()
+	cases := map[string]string{
+		"latest":       "1.21.0",
+		"1.20":         "1.20.5",
+		"1":            "1.21.0",
+		"<1.20":        "1.9.7",
+		">=1.10 <1.21": "1.20.5",
+	}
+	for constraint, want := range cases {
+		got, err := index.Resolve("go", constraint)
+		if err != nil {
+			t.Errorf("Resolve(go, %q) got error: %v", constraint, err)
+			continue
+		}
+		if got.Version != want {
+			t.Errorf("Resolve(go, %q) = %s, want %s", constraint, got.Version, want)
+		}
+	}
+
+	if _, err := index.Resolve("go", "1.22"); err == nil {
+		t.Error("got nil error for a version that isn't in the index")
+	}
+	if _, err := index.Resolve("rust", "latest"); err == nil {
+		t.Error("got nil error for a package that isn't in the index")
+	}
+}
+
+func TestIndexSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json.gz")
+	index := testIndex()
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(index, loaded); diff != "" {
+		t.Errorf("loaded index is different (-want +got):\n%s", diff)
+	}
+
+	if _, err := LoadIndex(filepath.Join(t.TempDir(), "missing")); err != ErrNoIndex {
+		t.Errorf("got error %v for missing index, want ErrNoIndex", err)
+	}
+}
+
+func TestClientFallsBackToIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "index.json.gz")
+	t.Setenv(envir.DevboxSearchHost, server.URL)
+	t.Setenv(envir.DevboxSearchIndex, path)
+
+	c := Client()
+	c.backoff = 0
+	c.cacheDir = t.TempDir()
+
+	// Without an index, the error of the search service is returned.
+	if _, err := c.Resolve("go", "1.20"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("got error %v without an index, want ErrUnavailable", err)
+	}
+
+	if err := testIndex().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Resolve("go", "1.20")
+	if err != nil {
+		t.Fatalf("got error with an index: %v", err)
+	}
+	if got.Version != "1.20.5" {
+		t.Errorf("got version %s, want 1.20.5", got.Version)
+	}
+}
+
+func TestClientOffline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json.gz")
+	if err := testIndex().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("got request %s, want no requests to the search service", r.URL)
+	}))
+	defer server.Close()
+	t.Setenv(envir.DevboxSearchHost, server.URL)
+	t.Setenv(envir.DevboxSearchIndex, path)
+	t.Setenv(envir.DevboxSearchOffline, "1")
+
+	results, err := Client().Search("gopls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.NumResults != 1 {
+		t.Errorf("got %d results, want 1", results.NumResults)
+	}
+}
+
+func TestForcedOffline(t *testing.T) {
+	t.Setenv(envir.XDGConfigHome, t.TempDir())
+	// Unset DEVBOX_SEARCH_OFFLINE, and restore it after the test.
+	t.Setenv(envir.DevboxSearchOffline, "")
+	os.Unsetenv(envir.DevboxSearchOffline)
+
+	if forcedOffline() {
+		t.Error("got offline without settings, want online")
+	}
+	if err := (&Settings{Offline: true}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !forcedOffline() {
+		t.Error("got online with offline settings, want offline")
+	}
+	t.Setenv(envir.DevboxSearchOffline, "0")
+	if forcedOffline() {
+		t.Error("got offline with DEVBOX_SEARCH_OFFLINE=0, want it to override the settings")
+	}
+}
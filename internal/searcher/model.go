@@ -3,6 +3,13 @@
 
 package searcher
 
+import (
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/semver"
+)
+
 type SearchResults struct {
 	NumResults int       `json:"num_results"`
 	Packages   []Package `json:"packages,omitempty"`
@@ -34,3 +41,46 @@ type PackageInfo struct {
 	AttrPaths    []string `json:"attr_paths"`
 	Version      string   `json:"version"`
 }
+
+// SystemNames returns the sorted names of the systems that the version is
+// available on.
+func (p *PackageVersion) SystemNames() []string {
+	names := maps.Keys(p.Systems)
+	slices.Sort(names)
+	return names
+}
+
+// AvailableOn returns true if the version is available on all the systems.
+func (p *PackageVersion) AvailableOn(systems ...string) bool {
+	for _, system := range systems {
+		if _, ok := p.Systems[system]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the results with only the versions that meet the constraint
+// and are available on all the systems. Packages without such versions are
+// removed. A nil constraint matches any version.
+func (r *SearchResults) Filter(constraint *semver.Constraint, systems []string) *SearchResults {
+	if constraint == nil && len(systems) == 0 {
+		return r
+	}
+	result := &SearchResults{}
+	for _, pkg := range r.Packages {
+		versions := []PackageVersion{}
+		for _, v := range pkg.Versions {
+			if (constraint == nil || constraint.Check(v.Version)) && v.AvailableOn(systems...) {
+				versions = append(versions, v)
+			}
+		}
+		if len(versions) > 0 {
+			pkg.Versions = versions
+			pkg.NumVersions = len(versions)
+			result.Packages = append(result.Packages, pkg)
+		}
+	}
+	result.NumResults = len(result.Packages)
+	return result
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package searcher
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/semver"
+)
+
+func TestSearchResultsFilter(t *testing.T) {
+	results := testIndex().Search("go")
+
+	versions := func(r *SearchResults) map[string][]string {
+		got := map[string][]string{}
+		for _, pkg := range r.Packages {
+			for _, v := range pkg.Versions {
+				got[pkg.Name] = append(got[pkg.Name], v.Version)
+			}
+		}
+		return got
+	}
+
+	constraint, err := semver.ParseConstraint(">=1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := results.Filter(constraint, nil)
+	want := map[string][]string{"go": {"1.21.0", "1.20.5"}}
+	if diff := cmp.Diff(want, versions(got)); diff != "" {
+		t.Errorf("wrong versions for %s (-want +got):\n%s", constraint, diff)
+	}
+	if got.NumResults != 1 || got.Packages[0].NumVersions != 2 {
+		t.Errorf("got %d results and %d versions of go, want 1 and 2",
+			got.NumResults, got.Packages[0].NumVersions)
+	}
+
+	got = results.Filter(nil, []string{"aarch64-darwin"})
+	want = map[string][]string{"go": {"1.20.5"}}
+	if diff := cmp.Diff(want, versions(got)); diff != "" {
+		t.Errorf("wrong versions for aarch64-darwin (-want +got):\n%s", diff)
+	}
+
+	// The original results must not be modified.
+	if len(results.Packages) != 3 || len(results.Packages[0].Versions) != 3 {
+		t.Errorf("original results were modified: %+v", results)
+	}
+}
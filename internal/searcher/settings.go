@@ -0,0 +1,75 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package searcher
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/xdg"
+)
+
+// Settings are the user's settings for searching and resolving packages.
+type Settings struct {
+	// Offline makes devbox always use the offline index instead of the search
+	// service. DEVBOX_SEARCH_OFFLINE overrides it.
+	Offline bool `json:"offline"`
+}
+
+// SettingsPath returns the path of the file with the user's settings.
+func SettingsPath() string {
+	return xdg.ConfigSubpath(filepath.FromSlash("devbox/search.json"))
+}
+
+// LoadSettings reads the user's settings. It returns the default settings if
+// they were never saved.
+func LoadSettings() (*Settings, error) {
+	settings := &Settings{}
+	data, err := os.ReadFile(SettingsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", SettingsPath())
+	}
+	return settings, nil
+}
+
+// Save writes the settings, so that they apply to later devbox commands.
+func (s *Settings) Save() error {
+	data, err := cuecfg.MarshalJSON(s)
+	if err != nil {
+		return err
+	}
+	path := SettingsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, data, 0644))
+}
+
+// forcedOffline returns true if DEVBOX_SEARCH_OFFLINE is set to a true value,
+// or if it isn't set and the user's settings enable offline mode.
+func forcedOffline() bool {
+	if env, ok := os.LookupEnv(envir.DevboxSearchOffline); ok {
+		offline, _ := strconv.ParseBool(env)
+		return offline
+	}
+	settings, err := LoadSettings()
+	if err != nil {
+		debug.Log("failed to load search settings: %v", err)
+		return false
+	}
+	return settings.Offline
+}
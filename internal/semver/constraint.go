@@ -0,0 +1,237 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Latest is the constraint that matches any version. Resolving it returns the
+// newest version.
+const Latest = "latest"
+
+type operator string
+
+const (
+	opPrefix operator = ""
+	opEqual  operator = "="
+	opNot    operator = "!="
+	opGT     operator = ">"
+	opGTE    operator = ">="
+	opLT     operator = "<"
+	opLTE    operator = "<="
+)
+
+// operators are ordered so that longer operators are parsed first.
+var operators = []string{"!=", ">=", "<=", "==", "=", ">", "<", "^", "~"}
+
+type condition struct {
+	op      operator
+	version string
+}
+
+func (c condition) check(version string) bool {
+	switch c.op {
+	case opPrefix:
+		return HasPrefix(version, c.version)
+	case opEqual:
+		return Compare(version, c.version) == 0
+	case opNot:
+		return Compare(version, c.version) != 0
+	case opGT:
+		return Compare(version, c.version) > 0
+	case opGTE:
+		return Compare(version, c.version) >= 0
+	case opLT:
+		return Compare(version, c.version) < 0
+	case opLTE:
+		return Compare(version, c.version) <= 0
+	default:
+		panic(fmt.Sprintf("invalid operator: %s", c.op))
+	}
+}
+
+// Constraint is a set of versions. It is written as one or more ranges
+// separated by "||", where a range is a list of conditions that must all be
+// met, separated by spaces or commas. A condition is one of:
+//
+//   - A version, or a version with a trailing ".x" or ".*", that matches the
+//     versions it is a prefix of: "1.20" and "1.20.x" match "1.20.5".
+//   - A comparison with =, !=, >, >=, < or <=, e.g. ">=1.20".
+//   - A caret range, which allows changes that don't modify the first non-zero
+//     component: "^1.20" is ">=1.20 <2" and "^0.3.1" is ">=0.3.1 <0.4".
+//   - A tilde range, which allows changes after the second component:
+//     "~1.20.3" is ">=1.20.3 <1.21" and "~1" is ">=1 <2".
+//
+// "latest", "*" and "" match any version.
+type Constraint struct {
+	raw    string
+	ranges [][]condition
+}
+
+// ParseConstraint parses a version constraint.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" || c.raw == Latest || c.raw == "*" {
+		return c, nil
+	}
+
+	for _, rng := range strings.Split(c.raw, "||") {
+		conditions := []condition{}
+		fields := strings.FieldsFunc(rng, func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			// Allow a space between an operator and its version: ">= 1.20".
+			if isOperator(field) && i+1 < len(fields) {
+				i++
+				field += fields[i]
+			}
+			conds, err := parseCondition(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+			}
+			conditions = append(conditions, conds...)
+		}
+		if len(conditions) == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q: empty range", s)
+		}
+		c.ranges = append(c.ranges, conditions)
+	}
+	return c, nil
+}
+
+// Check returns true if version meets the constraint.
+func (c *Constraint) Check(version string) bool {
+	if len(c.ranges) == 0 {
+		return true
+	}
+	for _, rng := range c.ranges {
+		matches := true
+		for _, cond := range rng {
+			if !cond.check(version) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// Best returns the newest of versions that meets the constraint, and false if
+// none does.
+func (c *Constraint) Best(versions []string) (string, bool) {
+	best, found := "", false
+	for _, v := range versions {
+		if c.Check(v) && (!found || Compare(v, best) > 0) {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
+
+// String returns the constraint as it was written.
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// IsRange returns true if s is a constraint that matches versions other than
+// the ones it is a prefix of, such as "^1.20" or ">=1.20 <1.22". Plain versions
+// like "1.20" or "1.20.x", and "latest", are not ranges.
+func IsRange(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.ContainsAny(s, "<>=!^~|, ")
+}
+
+func isOperator(s string) bool {
+	for _, op := range operators {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCondition(s string) ([]condition, error) {
+	op := ""
+	for _, o := range operators {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+	version := strings.TrimPrefix(strings.TrimPrefix(s[len(op):], "v"), "V")
+	if version == "" {
+		return nil, fmt.Errorf("missing version after %q", op)
+	}
+	if op != "" && op != "^" && op != "~" && trimWildcards(version) != version {
+		return nil, fmt.Errorf("wildcards can't be used with %q", op)
+	}
+	version = trimWildcards(version)
+	if version == "" || version == "x" || version == "*" {
+		return nil, fmt.Errorf("invalid version %q", s)
+	}
+
+	switch op {
+	case "":
+		return []condition{{op: opPrefix, version: version}}, nil
+	case "=", "==":
+		return []condition{{op: opEqual, version: version}}, nil
+	case "^", "~":
+		// Caret and tilde ranges are defined in terms of major, minor and patch
+		// numbers, so they only apply to numeric versions.
+		v, err := NewVersion(version)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a numeric version like 1.20.3, got %q", op, version)
+		}
+		upper := caretUpperBound(v.numbers())
+		if op == "~" {
+			upper = tildeUpperBound(v.numbers())
+		}
+		return []condition{
+			{op: opGTE, version: version},
+			{op: opLT, version: upper},
+		}, nil
+	default:
+		return []condition{{op: operator(op), version: version}}, nil
+	}
+}
+
+// caretUpperBound increments the first non-zero number of a version, and
+// drops the numbers after it.
+func caretUpperBound(numbers []int) string {
+	for i, n := range numbers {
+		if n != 0 || i == len(numbers)-1 {
+			return bump(numbers[:i+1])
+		}
+	}
+	return bump(numbers)
+}
+
+// tildeUpperBound increments the minor number of a version, or the major
+// number if there's no minor number, and drops the numbers after it.
+func tildeUpperBound(numbers []int) string {
+	if len(numbers) == 1 {
+		return bump(numbers)
+	}
+	return bump(numbers[:2])
+}
+
+// bump increments the last component of comps and joins them with dots.
+func bump(comps []int) string {
+	parts := make([]string, len(comps))
+	for i, n := range comps {
+		if i == len(comps)-1 {
+			n++
+		}
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
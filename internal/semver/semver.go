@@ -0,0 +1,131 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package semver compares the versions of nix packages, matches them against
+// version constraints such as "^1.20" or ">=3.10 <3.12", and parses the
+// versions written in the manifests of projects.
+//
+// Nix package versions don't always follow semantic versioning (e.g. "118.0",
+// "2023-05-01" or "3.12.0rc1"), so versions are compared the same way as nix's
+// builtins.compareVersions.
+package semver
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Compare returns -1, 0 or 1 if version a is older than, the same as, or newer
+// than version b. It follows the rules of nix's builtins.compareVersions:
+// versions are split into numeric and non-numeric components, numbers compare
+// numerically and are newer than strings, and "pre" is older than anything.
+func Compare(a, b string) int {
+	ac, bc := components(a), components(b)
+	for i := 0; i < len(ac) || i < len(bc); i++ {
+		var c1, c2 string
+		if i < len(ac) {
+			c1 = ac[i]
+		}
+		if i < len(bc) {
+			c2 = bc[i]
+		}
+		if componentLess(c1, c2) {
+			return -1
+		}
+		if componentLess(c2, c1) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// components splits a version at dots and dashes, and between digits and other
+// characters. For example, "3.12.0rc1" has the components 3, 12, 0, rc and 1.
+func components(version string) []string {
+	result := []string{}
+	start := -1
+	for i, r := range version {
+		if r == '.' || r == '-' {
+			if start >= 0 {
+				result = append(result, version[start:i])
+			}
+			start = -1
+			continue
+		}
+		if start >= 0 && unicode.IsDigit(r) != isDigit(version[start]) {
+			result = append(result, version[start:i])
+			start = -1
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		result = append(result, version[start:])
+	}
+	return result
+}
+
+func componentLess(c1, c2 string) bool {
+	n1, err1 := strconv.Atoi(c1)
+	n2, err2 := strconv.Atoi(c2)
+	switch {
+	case err1 == nil && err2 == nil:
+		return n1 < n2
+	case c1 == "" && err2 == nil:
+		return true
+	case c1 == "pre" && c2 != "pre":
+		return true
+	case c2 == "pre":
+		return false
+	case err2 == nil:
+		return true
+	case err1 == nil:
+		return false
+	default:
+		return c1 < c2
+	}
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// HasPrefix returns true if the components of prefix are the first components
+// of version. For example, "1.20" is a prefix of "1.20.5" but not of "1.2".
+func HasPrefix(version, prefix string) bool {
+	vc, pc := components(version), components(prefix)
+	if len(pc) > len(vc) {
+		return false
+	}
+	for i := range pc {
+		if pc[i] != vc[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Max returns the newest of the given versions, or "" if there are none.
+func Max(versions ...string) string {
+	result := ""
+	for i, v := range versions {
+		if i == 0 || Compare(v, result) > 0 {
+			result = v
+		}
+	}
+	return result
+}
+
+// trimWildcards removes trailing ".x" and ".*" components, so that "1.20.x"
+// becomes "1.20".
+func trimWildcards(version string) string {
+	for {
+		trimmed := strings.TrimSuffix(strings.TrimSuffix(version, ".x"), ".*")
+		if trimmed == version {
+			return version
+		}
+		version = trimmed
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package semver
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.20.5", "1.20.5", 0},
+		{"1.20.5", "1.21", -1},
+		{"1.9", "1.10", -1},
+		{"1.2", "1.20", -1},
+		{"1.2", "1.2.0", -1},
+		{"3.12.0pre1", "3.12.0", -1},
+		{"3.12.0", "3.12.0rc1", -1},
+		{"2.0a", "2.0", 1},
+		{"1.2.a", "1.2.1", -1},
+		{"2023-05-01", "2023-04-30", 1},
+		{"118.0", "99.0.1", 1},
+	}
+	for _, tc := range cases {
+		if got := Compare(tc.a, tc.b); got != tc.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if got := Compare(tc.b, tc.a); got != -tc.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tc.b, tc.a, got, -tc.want)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		matches    []string
+		rejects    []string
+	}{
+		{"latest", []string{"0.1", "118.0"}, nil},
+		{"1.20", []string{"1.20", "1.20.5"}, []string{"1.2", "1.21.0", "11.20"}},
+		{"1.20.x", []string{"1.20.5"}, []string{"1.21.0"}},
+		{"=1.20", []string{"1.20"}, []string{"1.20.5"}},
+		{">=1.20 <1.22", []string{"1.20", "1.21.9"}, []string{"1.19.9", "1.22", "1.22.1"}},
+		{">= 1.20, < 1.22", []string{"1.21"}, []string{"1.22"}},
+		{"^1.20.3", []string{"1.20.3", "1.99"}, []string{"1.20.2", "2.0"}},
+		{"^0.3.1", []string{"0.3.9"}, []string{"0.4.0"}},
+		{"~1.20.3", []string{"1.20.9"}, []string{"1.21.0"}},
+		{"~1", []string{"1.99"}, []string{"2.0"}},
+		{"<3.10 || >=3.12", []string{"3.9.18", "3.12.1"}, []string{"3.10.2", "3.11.0"}},
+		{"!=3.11.0 3.11", []string{"3.11.1"}, []string{"3.11.0"}},
+	}
+	for _, tc := range cases {
+		c, err := ParseConstraint(tc.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) got error: %v", tc.constraint, err)
+			continue
+		}
+		for _, v := range tc.matches {
+			if !c.Check(v) {
+				t.Errorf("%q doesn't match %q, want match", tc.constraint, v)
+			}
+		}
+		for _, v := range tc.rejects {
+			if c.Check(v) {
+				t.Errorf("%q matches %q, want no match", tc.constraint, v)
+			}
+		}
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, s := range []string{">=", ">=1.20 ||", "<1.x", "x", "^1.20rc1", "~2023-05-01"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) got nil error, want error", s)
+		}
+	}
+}
+
+func TestBest(t *testing.T) {
+	c, err := ParseConstraint("^1.20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := []string{"1.19.3", "1.21.1", "1.20.14", "2.0.1"}
+	if got, ok := c.Best(versions); !ok || got != "1.21.1" {
+		t.Errorf("got best version %q, %v, want 1.21.1", got, ok)
+	}
+	c, _ = ParseConstraint("^3")
+	if _, ok := c.Best(versions); ok {
+		t.Error("got a version for ^3, want none")
+	}
+}
+
+func TestIsRange(t *testing.T) {
+	for s, want := range map[string]bool{
+		"1.20":         false,
+		"1.20.x":       false,
+		"latest":       false,
+		"^1.20":        true,
+		">=1.20 <1.22": true,
+		"1.2 || 1.4":   true,
+	} {
+		if got := IsRange(s); got != want {
+			t.Errorf("IsRange(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -1,16 +1,19 @@
 // Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
 // Use of this source code is governed by the license in the LICENSE file.
 
-package analyzer
+package semver
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
-// Version handles very simple numeric semver versions (e.g. "1.2.3")
+// Version handles very simple numeric semver versions (e.g. "1.2.3"), as
+// written in the manifests of projects. Unlike Compare and Constraint, it
+// doesn't handle nix versions with non-numeric components.
 type Version string
 
 func NewVersion(v string) (*Version, error) {
@@ -34,6 +37,20 @@ func (v Version) parts() []string {
 	return nil
 }
 
+// numbers returns the major, minor and patch numbers of the version, as many
+// as it has.
+func (v Version) numbers() []int {
+	result := []int{}
+	for _, part := range v.parts() {
+		n, err := strconv.Atoi(strings.TrimPrefix(part, "."))
+		if err != nil {
+			break
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
 func (v Version) Exact() string {
 	return strings.Join(v.parts(), "")
 }
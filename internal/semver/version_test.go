@@ -1,7 +1,7 @@
 // Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
 // Use of this source code is governed by the license in the LICENSE file.
 
-package analyzer
+package semver
 
 import (
 	"testing"
@@ -32,3 +32,23 @@ func TestVersionExact(t *testing.T) {
 		)
 	}
 }
+
+func TestVersionNumbers(t *testing.T) {
+	cases := []struct {
+		version string
+		numbers []int
+	}{
+		{"1", []int{1}},
+		{"1.20", []int{1, 20}},
+		{"^0.3.1", []int{0, 3, 1}},
+		{">=18, <20", []int{18}},
+	}
+
+	for _, tc := range cases {
+		t.Run(
+			tc.version, func(t *testing.T) {
+				require.Equal(t, tc.numbers, Version(tc.version).numbers())
+			},
+		)
+	}
+}
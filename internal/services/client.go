@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/f1bonacc1/process-compose/src/types"
 )
@@ -20,6 +21,16 @@ type Process struct {
 	Name     string
 	Status   string
 	ExitCode int
+	// Health is the result of the readiness probe of the process, or
+	// types.ProcessHealthUnknown if it doesn't have one.
+	Health string
+	// Pid is zero if the process isn't running.
+	Pid      int
+	Restarts int
+	// Uptime is how long the process has been running, with the precision
+	// that process-compose reports it with: seconds for the first 3 minutes,
+	// then minutes, then hours after a day.
+	Uptime time.Duration
 }
 
 func StartServices(ctx context.Context, w io.Writer, serviceName string, projectDir string) error {
@@ -95,6 +106,10 @@ func ListServices(ctx context.Context, projectDir string, w io.Writer) ([]Proces
 				Name:     process.Name,
 				Status:   process.Status,
 				ExitCode: process.ExitCode,
+				Health:   process.Health,
+				Pid:      process.Pid,
+				Restarts: process.Restarts,
+				Uptime:   parseUptime(process.SystemTime),
 			})
 		}
 		return results, nil
@@ -103,6 +118,16 @@ func ListServices(ctx context.Context, projectDir string, w io.Writer) ([]Proces
 	}
 }
 
+// parseUptime parses the system time of a process-compose process, like
+// "42s", "5m", "3h12m" or "50h". It returns zero if the process isn't running.
+func parseUptime(systemTime string) time.Duration {
+	uptime, err := time.ParseDuration(systemTime)
+	if err != nil {
+		return 0
+	}
+	return uptime
+}
+
 func clientRequest(path string, method string, projectDir string) (string, int, error) {
 	port, err := GetProcessManagerPort(projectDir)
 	if err != nil {
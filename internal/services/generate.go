@@ -0,0 +1,174 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/f1bonacc1/process-compose/src/health"
+	"github.com/f1bonacc1/process-compose/src/types"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devconfig"
+)
+
+// generatedProcessComposePath is the process-compose file, relative to the
+// project, that devbox generates from the services in devbox.json.
+const generatedProcessComposePath = ".devbox/gen/process-compose.yaml"
+
+// FromConfig translates the services declared in devbox.json into a
+// process-compose file in .devbox/gen, and returns them. The file is only
+// written if it changed.
+func FromConfig(projectDir string, cfgs map[string]*devconfig.ServiceConfig) (Services, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	project, err := generateProcessCompose(projectDir, cfgs)
+	if err != nil {
+		return nil, err
+	}
+	// cuecfg.Marshal isn't used because cue drops fields of process-compose's
+	// types.
+	data, err := yaml.Marshal(project)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	path := filepath.Join(projectDir, generatedProcessComposePath)
+	if old, err := os.ReadFile(path); err != nil || !bytes.Equal(old, data) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	svcs := Services{}
+	for name := range cfgs {
+		svcs[name] = Service{Name: name, ProcessComposePath: path}
+	}
+	return svcs, nil
+}
+
+func generateProcessCompose(
+	projectDir string,
+	cfgs map[string]*devconfig.ServiceConfig,
+) (*types.Project, error) {
+	project := &types.Project{
+		Version:   "0.5",
+		Processes: types.Processes{},
+	}
+	for name, cfg := range cfgs {
+		if cfg.Command == "" {
+			return nil, usererr.New("service %q in devbox.json has no command", name)
+		}
+		process := types.ProcessConfig{
+			Name:       name,
+			Command:    cfg.Command,
+			WorkingDir: projectDir,
+		}
+		if cfg.Cwd != "" {
+			process.WorkingDir = cfg.Cwd
+			if !filepath.IsAbs(cfg.Cwd) {
+				process.WorkingDir = filepath.Join(projectDir, cfg.Cwd)
+			}
+		}
+
+		envNames := maps.Keys(cfg.Env)
+		slices.Sort(envNames)
+		for _, envName := range envNames {
+			process.Environment = append(process.Environment, envName+"="+cfg.Env[envName])
+		}
+
+		switch cfg.Restart {
+		case "", types.RestartPolicyNo:
+		case types.RestartPolicyAlways, types.RestartPolicyOnFailure, types.RestartPolicyExitOnFailure:
+			process.RestartPolicy.Restart = cfg.Restart
+		default:
+			return nil, usererr.New(
+				"service %q in devbox.json has an invalid restart policy %q: "+
+					"use no, always, on_failure or exit_on_failure",
+				name, cfg.Restart,
+			)
+		}
+
+		for _, dep := range cfg.DependsOn {
+			if process.DependsOn == nil {
+				process.DependsOn = types.DependsOnConfig{}
+			}
+			condition := types.ProcessConditionStarted
+			// Dependencies can also be services of plugins or of the project's
+			// process-compose.yaml, which devbox doesn't know the probes of.
+			if depCfg := cfgs[dep]; depCfg != nil && depCfg.ReadinessProbe != nil {
+				condition = types.ProcessConditionHealthy
+			}
+			process.DependsOn[dep] = types.ProcessDependency{Condition: condition}
+		}
+
+		if cfg.ReadinessProbe != nil {
+			probe, err := processComposeProbe(cfg.ReadinessProbe)
+			if err != nil {
+				return nil, usererr.New(
+					"service %q in devbox.json has an invalid readiness probe: %v", name, err)
+			}
+			process.ReadinessProbe = probe
+		}
+		project.Processes[name] = process
+	}
+	return project, nil
+}
+
+func processComposeProbe(cfg *devconfig.ProbeConfig) (*health.Probe, error) {
+	probe := &health.Probe{
+		InitialDelay:     cfg.InitialDelaySeconds,
+		PeriodSeconds:    cfg.PeriodSeconds,
+		TimeoutSeconds:   cfg.TimeoutSeconds,
+		FailureThreshold: cfg.FailureThreshold,
+	}
+	switch {
+	case cfg.Exec != "" && cfg.HTTPGet != "":
+		return nil, errors.New("set either exec or http_get, not both")
+	case cfg.Exec != "":
+		probe.Exec = &health.ExecProbe{Command: cfg.Exec}
+	case cfg.HTTPGet != "":
+		u, err := url.Parse(cfg.HTTPGet)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, errors.Errorf("http_get must be an http:// or https:// URL, got %q", cfg.HTTPGet)
+		}
+		port := 80
+		if u.Scheme == "https" {
+			port = 443
+		}
+		if u.Port() != "" {
+			if port, err = strconv.Atoi(u.Port()); err != nil {
+				return nil, err
+			}
+		}
+		path := u.EscapedPath()
+		if u.RawQuery != "" {
+			path += "?" + u.RawQuery
+		}
+		probe.HttpGet = &health.HttpProbe{
+			Host:   u.Hostname(),
+			Path:   path,
+			Scheme: u.Scheme,
+			Port:   port,
+		}
+	default:
+		return nil, errors.New("set exec or http_get")
+	}
+	return probe, nil
+}
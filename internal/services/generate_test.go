@@ -0,0 +1,136 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/f1bonacc1/process-compose/src/health"
+	"github.com/f1bonacc1/process-compose/src/types"
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/devconfig"
+)
+
+func TestGenerateProcessCompose(t *testing.T) {
+	cfgs := map[string]*devconfig.ServiceConfig{
+		"db": {
+			Command:        "postgres",
+			Restart:        "always",
+			ReadinessProbe: &devconfig.ProbeConfig{Exec: "pg_isready", PeriodSeconds: 2},
+		},
+		"web": {
+			Command:   "npm start",
+			Env:       map[string]string{"PORT": "8080", "HOST": "localhost"},
+			Cwd:       "web",
+			DependsOn: []string{"db", "worker"},
+			ReadinessProbe: &devconfig.ProbeConfig{
+				HTTPGet: "http://localhost:8080/health?full=1",
+			},
+		},
+		"worker": {Command: "python worker.py"},
+	}
+
+	got, err := generateProcessCompose("/project", cfgs)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	want := types.Processes{
+		"db": {
+			Name:          "db",
+			Command:       "postgres",
+			WorkingDir:    "/project",
+			RestartPolicy: types.RestartPolicyConfig{Restart: "always"},
+			ReadinessProbe: &health.Probe{
+				Exec:          &health.ExecProbe{Command: "pg_isready"},
+				PeriodSeconds: 2,
+			},
+		},
+		"web": {
+			Name:        "web",
+			Command:     "npm start",
+			WorkingDir:  "/project/web",
+			Environment: types.Environment{"HOST=localhost", "PORT=8080"},
+			DependsOn: types.DependsOnConfig{
+				"db":     {Condition: types.ProcessConditionHealthy},
+				"worker": {Condition: types.ProcessConditionStarted},
+			},
+			ReadinessProbe: &health.Probe{
+				HttpGet: &health.HttpProbe{
+					Host:   "localhost",
+					Path:   "/health?full=1",
+					Scheme: "http",
+					Port:   8080,
+				},
+			},
+		},
+		"worker": {
+			Name:       "worker",
+			Command:    "python worker.py",
+			WorkingDir: "/project",
+		},
+	}
+	if diff := cmp.Diff(want, got.Processes); diff != "" {
+		t.Errorf("wrong processes (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateProcessComposeErrors(t *testing.T) {
+	cases := map[string]*devconfig.ServiceConfig{
+		"no command":     {},
+		"invalid policy": {Command: "true", Restart: "sometimes"},
+		"two probes": {
+			Command:        "true",
+			ReadinessProbe: &devconfig.ProbeConfig{Exec: "true", HTTPGet: "http://localhost"},
+		},
+		"invalid url": {
+			Command:        "true",
+			ReadinessProbe: &devconfig.ProbeConfig{HTTPGet: "localhost:8080"},
+		},
+	}
+	for name, cfg := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfgs := map[string]*devconfig.ServiceConfig{"svc": cfg}
+			if _, err := generateProcessCompose("/project", cfgs); err == nil {
+				t.Error("got nil error, want error")
+			}
+		})
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	cfgs := map[string]*devconfig.ServiceConfig{
+		"web": {
+			Command:        "npm start",
+			Restart:        "on_failure",
+			ReadinessProbe: &devconfig.ProbeConfig{HTTPGet: "http://localhost:8080/"},
+		},
+	}
+
+	svcs, err := FromConfig(projectDir, cfgs)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	path := filepath.Join(projectDir, generatedProcessComposePath)
+	want := Services{"web": {Name: "web", ProcessComposePath: path}}
+	if diff := cmp.Diff(want, svcs); diff != "" {
+		t.Errorf("wrong services (-want +got):\n%s", diff)
+	}
+
+	// The generated file must be readable like a user's process-compose.yaml.
+	wantProject, err := generateProcessCompose(projectDir, cfgs)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	gotProject := &types.Project{}
+	if err := cuecfg.ParseFile(path, gotProject); err != nil {
+		t.Fatalf("got error parsing %s: %v", path, err)
+	}
+	if diff := cmp.Diff(wantProject, gotProject); diff != "" {
+		t.Errorf("wrong generated file (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/debug"
+)
+
+// instancePath is the file, relative to the project, that records the
+// project's running process-compose instance.
+const instancePath = ".devbox/process-compose.json"
+
+// instance is the process-compose instance of a project.
+type instance struct {
+	Pid  int `json:"pid"`
+	Port int `json:"port"`
+	// StartTime identifies when the process with Pid started, so that a
+	// process that reuses the PID of a stopped instance isn't mistaken for
+	// it. Its format depends on the OS.
+	StartTime string    `json:"start_time"`
+	Started   time.Time `json:"started"`
+	// Files are the process-compose files that the instance was started
+	// with. They're empty for instances started by older versions of devbox.
+	Files []string `json:"files,omitempty"`
+}
+
+// readInstance returns the instance of process-compose that was started for
+// the project in projectDir, or nil if there's none. Instances that stopped
+// without removing their file are removed and not returned.
+func readInstance(projectDir string) (*instance, error) {
+	if _, err := ImportLegacyInstances(); err != nil {
+		debug.Log("failed to import the process-compose instances of older versions of devbox: %v", err)
+	}
+	path := filepath.Join(projectDir, instancePath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	inst := &instance{}
+	if err := json.Unmarshal(data, inst); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+
+	alive, err := inst.isAlive()
+	if err != nil {
+		return nil, err
+	}
+	if !alive {
+		return nil, removeInstance(projectDir, inst.Pid)
+	}
+	return inst, nil
+}
+
+func writeInstance(projectDir string, inst *instance) error {
+	data, err := cuecfg.MarshalJSON(inst)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(projectDir, instancePath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, data, 0644))
+}
+
+// removeInstance removes the instance file of a project if it's still the one
+// of the process with pid, so that a newer instance isn't forgotten.
+func removeInstance(projectDir string, pid int) error {
+	path := filepath.Join(projectDir, instancePath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	inst := &instance{}
+	if err := json.Unmarshal(data, inst); err == nil && inst.Pid != pid {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (i *instance) isAlive() (bool, error) {
+	startTime, err := processStartTime(i.Pid)
+	if err != nil {
+		return false, err
+	}
+	return startTime != "" && startTime == i.StartTime, nil
+}
+
+// processStartTime returns when the process with pid started, or "" if
+// there's no such process. The result is only meant to be compared with
+// other results of processStartTime.
+func processStartTime(pid int) (string, error) {
+	// On Linux, the 22nd field of /proc/<pid>/stat is the start time of the
+	// process in clock ticks since boot. The second field is the name of the
+	// executable in parentheses, which can contain spaces.
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err == nil {
+		fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+		if len(fields) < 20 {
+			return "", errors.Errorf("unexpected format of /proc/%d/stat", pid)
+		}
+		return fields[19], nil
+	}
+	if _, statErr := os.Stat("/proc/self/stat"); statErr == nil {
+		// There's a /proc, but no process with pid.
+		return "", nil
+	}
+
+	// Other systems, like macOS, don't have /proc.
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(bytes.TrimSpace(out)) == 0 {
+		// ps exits with 1 if there's no process with pid.
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// availablePort returns a TCP port that no process is listening on.
+func availablePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/envir"
+)
+
+// exitedPid returns the PID of a process that has exited.
+func exitedPid(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("can't run true: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestProcessStartTime(t *testing.T) {
+	startTime, err := processStartTime(os.Getpid())
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if startTime == "" {
+		t.Fatal("got empty start time for the running test process")
+	}
+	again, err := processStartTime(os.Getpid())
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if again != startTime {
+		t.Errorf("got start time %q, then %q, want them to be equal", startTime, again)
+	}
+
+	startTime, err = processStartTime(exitedPid(t))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if startTime != "" {
+		t.Errorf("got start time %q for an exited process, want empty", startTime)
+	}
+}
+
+func TestReadInstance(t *testing.T) {
+	t.Setenv(envir.XDGDataHome, t.TempDir())
+	projectDir := t.TempDir()
+	inst, err := readInstance(projectDir)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if inst != nil {
+		t.Fatalf("got instance %+v without an instance file, want nil", inst)
+	}
+
+	startTime, err := processStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &instance{
+		Pid:       os.Getpid(),
+		Port:      8260,
+		StartTime: startTime,
+		Started:   time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if err := writeInstance(projectDir, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readInstance(projectDir)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong instance (-want +got):\n%s", diff)
+	}
+
+	running := RunningInstances(io.Discard, []string{projectDir, t.TempDir()})
+	wantRunning := []RunningInstance{{
+		ProjectDir: projectDir,
+		Pid:        want.Pid,
+		Port:       want.Port,
+		Started:    want.Started,
+	}}
+	if diff := cmp.Diff(wantRunning, running); diff != "" {
+		t.Errorf("wrong running instances (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadInstanceRemovesStaleInstances(t *testing.T) {
+	t.Setenv(envir.XDGDataHome, t.TempDir())
+	startTime, err := processStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]*instance{
+		"exited process": {Pid: exitedPid(t), Port: 8260, StartTime: startTime},
+		// The PID of a stopped instance was reused by another process.
+		"reused pid": {Pid: os.Getpid(), Port: 8260, StartTime: startTime + "0"},
+	}
+	for name, stale := range cases {
+		t.Run(name, func(t *testing.T) {
+			projectDir := t.TempDir()
+			if err := writeInstance(projectDir, stale); err != nil {
+				t.Fatal(err)
+			}
+			inst, err := readInstance(projectDir)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if inst != nil {
+				t.Errorf("got stale instance %+v, want nil", inst)
+			}
+			if _, err := os.Stat(filepath.Join(projectDir, instancePath)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("got stat error %v for the stale instance file, want it to be removed", err)
+			}
+		})
+	}
+}
+
+func TestRemoveInstanceKeepsNewerInstance(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := writeInstance(projectDir, &instance{Pid: 2, Port: 8260}); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeInstance(projectDir, 1); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, instancePath)); err != nil {
+		t.Errorf("got stat error %v, want the instance of another process to be kept", err)
+	}
+	if err := removeInstance(projectDir, 2); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, instancePath)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got stat error %v, want the instance file to be removed", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/xdg"
+)
+
+// legacyInstancesPath is the file, relative to the XDG data directory, in
+// which older versions of devbox recorded the process-compose instances of all
+// projects, keyed by project directory.
+var legacyInstancesPath = filepath.Join("devbox", "global", "process-compose.json")
+
+var (
+	legacyImport     sync.Once
+	legacyImportDirs []string
+	legacyImportErr  error
+)
+
+// ImportLegacyInstances moves the instances that older versions of devbox
+// recorded globally into the instance files of their projects, so that
+// process-compose instances that were started before upgrading devbox can
+// still be found and stopped. The global file is removed afterwards, so the
+// import only happens once. It returns the directories of the projects whose
+// instances were imported.
+func ImportLegacyInstances() ([]string, error) {
+	legacyImport.Do(func() {
+		legacyImportDirs, legacyImportErr = importLegacyInstances(xdg.DataSubpath(legacyInstancesPath))
+	})
+	return legacyImportDirs, legacyImportErr
+}
+
+func importLegacyInstances(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Older versions of devbox lock the file while they update it.
+	if err := fileutil.LockFile(file, fileLockTimeout); err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	legacy := map[string]struct {
+		Pid  int `json:"pid"`
+		Port int `json:"port"`
+	}{}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		// Older versions of devbox also ignored a file they couldn't parse.
+		debug.Log("failed to parse %s: %v", path, err)
+	}
+
+	dirs := []string{}
+	for dir, inst := range legacy {
+		imported, err := importLegacyInstance(dir, inst.Pid, inst.Port)
+		if err != nil {
+			debug.Log("failed to import the process-compose instance of %s: %v", dir, err)
+			continue
+		}
+		if imported {
+			dirs = append(dirs, dir)
+		}
+	}
+	slices.Sort(dirs)
+	return dirs, errors.WithStack(os.Remove(path))
+}
+
+// importLegacyInstance records the process-compose instance with pid and port
+// in the project in dir, unless the project already has an instance or the
+// instance stopped.
+func importLegacyInstance(dir string, pid, port int) (bool, error) {
+	if fileutil.Exists(filepath.Join(dir, instancePath)) {
+		return false, nil
+	}
+	startTime, err := processStartTime(pid)
+	if err != nil || startTime == "" {
+		return false, err
+	}
+	// Older versions didn't record when process-compose started, so a
+	// process that reused its PID is told apart by checking that it listens
+	// on the instance's port.
+	ports, err := ListeningPorts(pid)
+	if err != nil {
+		return false, err
+	}
+	if !slices.Contains(ports, port) {
+		return false, nil
+	}
+	return true, writeInstance(dir, &instance{Pid: pid, Port: port, StartTime: startTime})
+}
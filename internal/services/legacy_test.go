@@ -0,0 +1,74 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/envir"
+)
+
+func TestImportLegacyInstances(t *testing.T) {
+	t.Setenv(envir.XDGDataHome, t.TempDir())
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("only runs on systems with /proc")
+	}
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	running, stopped, reused, current := t.TempDir(), t.TempDir(), t.TempDir(), t.TempDir()
+	if err := writeInstance(current, &instance{Pid: os.Getpid(), Port: 8260}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "process-compose.json")
+	legacy := fmt.Sprintf(`{
+  %q: {"pid": %d, "port": %d},
+  %q: {"pid": %d, "port": %d},
+  %q: {"pid": %d, "port": %d},
+  %q: {"pid": %d, "port": %d}
+}`,
+		running, os.Getpid(), port,
+		stopped, exitedPid(t), port,
+		// The PID was reused by a process that doesn't listen on the port.
+		reused, os.Getpid(), port+1,
+		current, os.Getpid(), port,
+	)
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, err := importLegacyInstances(path)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if diff := cmp.Diff([]string{running}, dirs); diff != "" {
+		t.Errorf("wrong imported projects (-want +got):\n%s", diff)
+	}
+	inst, err := readInstance(running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst == nil || inst.Pid != os.Getpid() || inst.Port != port {
+		t.Errorf("got instance %+v, want the legacy instance with pid %d and port %d", inst, os.Getpid(), port)
+	}
+	for _, dir := range []string{stopped, reused} {
+		if _, err := os.Stat(filepath.Join(dir, instancePath)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("got stat error %v for %s, want no instance to be imported", err, dir)
+		}
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got stat error %v, want the legacy file to be removed", err)
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+)
+
+const (
+	// logsDir is the directory, relative to the project, with the log files
+	// of the services.
+	logsDir = ".devbox/logs"
+	// processComposeLogPath is the log, relative to the project, of
+	// process-compose itself when it runs in the background. It's outside of
+	// logsDir so that it can't collide with the log of a service.
+	processComposeLogPath = ".devbox/process-compose.log"
+	// logLocationsPath is the process-compose file, relative to the project,
+	// that sets the log file of each service. It's passed after the other
+	// files, so that process-compose merges it into their processes.
+	logLocationsPath = ".devbox/gen/process-compose-logs.yaml"
+	// maxRotatedLogs is how many previous runs are kept for each log.
+	maxRotatedLogs = 3
+	// maxLogLines is more lines than process-compose keeps in memory for a
+	// service, so that requesting it returns all of them.
+	maxLogLines      = 100_000
+	logsPollInterval = time.Second
+)
+
+// LogsOpts selects the logs that PrintLogs prints.
+type LogsOpts struct {
+	// Follow prints new lines as the services write them, until ctx is done.
+	Follow bool
+	// Since also prints the logs of the previous runs of the services that
+	// were written to within this duration. Only the logs of the latest run
+	// are printed if it's zero.
+	Since time.Duration
+}
+
+// logPath returns the path of the log file of a service's latest run.
+// Previous runs are in the same path, suffixed with .1 for the most recent
+// one, .2, and so on. The log of process-compose is rotated the same way.
+func logPath(projectDir, name string) string {
+	return filepath.Join(projectDir, logsDir, name+".log")
+}
+
+// rotateLog renames the log of the latest run to make room for a new run,
+// and deletes the oldest run if there are more than maxRotatedLogs. Empty logs
+// aren't rotated.
+func rotateLog(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.Size() == 0) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for i := maxRotatedLogs - 1; i >= 1; i-- {
+		err := os.Rename(rotatedLogPath(path, i), rotatedLogPath(path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(os.Rename(path, rotatedLogPath(path, 1)))
+}
+
+func rotatedLogPath(path string, run int) string {
+	return path + "." + strconv.Itoa(run)
+}
+
+// writeLogLocations rotates the logs of process-compose and of the services,
+// and writes a process-compose file that sets the log file of each service.
+// It returns the path of that file.
+func writeLogLocations(projectDir string, svcs Services) (string, error) {
+	if err := os.MkdirAll(filepath.Join(projectDir, logsDir), 0755); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err := rotateLog(filepath.Join(projectDir, processComposeLogPath)); err != nil {
+		return "", err
+	}
+
+	type logLocation struct {
+		LogLocation string `yaml:"log_location"`
+	}
+	processes := map[string]logLocation{}
+	for name := range svcs {
+		path := logPath(projectDir, name)
+		if err := rotateLog(path); err != nil {
+			return "", err
+		}
+		processes[name] = logLocation{LogLocation: path}
+	}
+	data, err := yaml.Marshal(map[string]any{"processes": processes})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	path := filepath.Join(projectDir, logLocationsPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return path, errors.WithStack(os.WriteFile(path, data, 0644))
+}
+
+// PrintLogs prints the logs of the named services. Lines are prefixed with
+// the name of their service if there are several services.
+//
+// The logs of the latest run come from process-compose if it's running,
+// because it buffers the lines it writes to the log files. Otherwise, they
+// come from the log files. Following the logs requires process-compose to be
+// running.
+func PrintLogs(
+	ctx context.Context,
+	w io.Writer,
+	projectDir string,
+	names []string,
+	opts LogsOpts,
+) error {
+	running := ProcessManagerIsRunning(projectDir)
+	if opts.Follow && !running {
+		return usererr.New("process-compose is not running. To start it, run `devbox services up`")
+	}
+
+	out := &logWriter{w: w, prefix: len(names) > 1}
+	latest := map[string][]string{}
+	for _, name := range names {
+		if opts.Since > 0 {
+			if err := printPreviousLogs(out, projectDir, name, time.Now().Add(-opts.Since)); err != nil {
+				return err
+			}
+		}
+
+		var lines []string
+		var err error
+		if running {
+			lines, err = fetchLogs(projectDir, name)
+		} else {
+			lines, err = readLogFile(logPath(projectDir, name))
+		}
+		if err != nil {
+			return err
+		}
+		out.print(name, lines)
+		latest[name] = lines
+	}
+	if !opts.Follow {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			errs <- followLogs(ctx, out, projectDir, name, latest[name])
+		}(name)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPreviousLogs prints the logs of the previous runs of a service that
+// were written to after since, oldest first.
+func printPreviousLogs(out *logWriter, projectDir, name string, since time.Time) error {
+	path := logPath(projectDir, name)
+	for run := maxRotatedLogs; run >= 1; run-- {
+		info, err := os.Stat(rotatedLogPath(path, run))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if info.ModTime().Before(since) {
+			continue
+		}
+		lines, err := readLogFile(rotatedLogPath(path, run))
+		if err != nil {
+			return err
+		}
+		out.print(name, lines)
+	}
+	return nil
+}
+
+// followLogs polls process-compose for the new lines of a service until ctx
+// is done or process-compose stops. prev are the lines that were already
+// printed.
+func followLogs(ctx context.Context, out *logWriter, projectDir, name string, prev []string) error {
+	ticker := time.NewTicker(logsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if !ProcessManagerIsRunning(projectDir) {
+			return nil
+		}
+		lines, err := fetchLogs(projectDir, name)
+		if err != nil {
+			return err
+		}
+		out.print(name, newLogLines(prev, lines))
+		prev = lines
+	}
+}
+
+// newLogLines returns the lines of cur that aren't in prev, given that both
+// are the latest lines of the same log and that cur was fetched after prev.
+// process-compose drops the oldest lines of a log when it grows too long, so
+// cur may start with any suffix of prev.
+func newLogLines(prev, cur []string) []string {
+	for start := 0; start < len(prev); start++ {
+		overlap := prev[start:]
+		if len(overlap) <= len(cur) && slices.Equal(overlap, cur[:len(overlap)]) {
+			return cur[len(overlap):]
+		}
+	}
+	return cur
+}
+
+// fetchLogs returns the lines that process-compose keeps in memory for a
+// service.
+func fetchLogs(projectDir, name string) ([]string, error) {
+	path := fmt.Sprintf("/process/logs/%s/%d/0", url.PathEscape(name), maxLogLines)
+	body, status, err := clientRequest(path, http.MethodGet, projectDir)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("unable to get the logs of service %s: %s", name, body)
+	}
+	resp := struct {
+		Logs []string `json:"logs"`
+	}{}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return resp.Logs, nil
+}
+
+// readLogFile returns the messages of a log file that process-compose wrote,
+// which has a JSON object per line. Lines that aren't JSON are returned as
+// is. It returns nil if the file doesn't exist.
+func readLogFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		entry := struct {
+			Message *string `json:"message"`
+		}{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Message == nil {
+			lines = append(lines, scanner.Text())
+			continue
+		}
+		lines = append(lines, *entry.Message)
+	}
+	return lines, errors.WithStack(scanner.Err())
+}
+
+// logWriter writes log lines of several services from several goroutines.
+type logWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix bool
+}
+
+func (l *logWriter) print(name string, lines []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range lines {
+		if l.prefix {
+			fmt.Fprintf(l.w, "%s | %s\n", name, line)
+		} else {
+			fmt.Fprintln(l.w, line)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewLogLines(t *testing.T) {
+	cases := []struct {
+		name      string
+		prev, cur []string
+		want      []string
+	}{
+		{"first fetch", nil, []string{"a", "b"}, []string{"a", "b"}},
+		{"no new lines", []string{"a", "b"}, []string{"a", "b"}, []string{}},
+		{"new lines", []string{"a", "b"}, []string{"a", "b", "c"}, []string{"c"}},
+		{"repeated lines", []string{"a", "a"}, []string{"a", "a", "a"}, []string{"a"}},
+		{"oldest lines dropped", []string{"a", "b", "c"}, []string{"c", "d"}, []string{"d"}},
+		{"no overlap", []string{"a", "b"}, []string{"c", "d"}, []string{"c", "d"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, newLogLines(tc.prev, tc.cur)); diff != "" {
+				t.Errorf("wrong new lines (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteLogLocationsRotatesLogs(t *testing.T) {
+	projectDir := t.TempDir()
+	svcs := Services{"web": {Name: "web"}}
+	webLog := logPath(projectDir, "web")
+
+	// Each run writes its number to the log, and the next run rotates it.
+	for run := 1; run <= maxRotatedLogs+2; run++ {
+		path, err := writeLogLocations(projectDir, svcs)
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if run == 1 {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), webLog) {
+				t.Errorf("got log locations %s, want them to contain %s", data, webLog)
+			}
+		}
+		line := fmt.Sprintf(`{"level":"info","process":"web","replica":0,"message":"run %d"}`, run)
+		if err := os.WriteFile(webLog, []byte(line+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string][]string{
+		"web.log":   {"run 5"},
+		"web.log.1": {"run 4"},
+		"web.log.2": {"run 3"},
+		"web.log.3": {"run 2"},
+	}
+	got := map[string][]string{}
+	matches, err := filepath.Glob(webLog + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, match := range matches {
+		if got[filepath.Base(match)], err = readLogFile(match); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong logs (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.log")
+	content := `{"level":"info","process":"web","replica":0,"message":"listening on :8080"}
+not json
+{"level":"error","process":"web","replica":0,"message":""}
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readLogFile(path)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	want := []string{"listening on :8080", "not json", ""}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong lines (-want +got):\n%s", diff)
+	}
+
+	if got, err := readLogFile(path + ".missing"); err != nil || got != nil {
+		t.Errorf("got %q, %v for a missing file, want nil, nil", got, err)
+	}
+}
+
+func TestServiceLogDoesNotCollideWithProcessComposeLog(t *testing.T) {
+	projectDir := t.TempDir()
+	svcs := Services{"process-compose": {Name: "process-compose"}}
+	pcLog := filepath.Join(projectDir, processComposeLogPath)
+	if err := os.MkdirAll(filepath.Dir(pcLog), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pcLog, []byte("started\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeLogLocations(projectDir, svcs); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if svcLog := logPath(projectDir, "process-compose"); svcLog == pcLog {
+		t.Errorf("got log %s for the service, want it to differ from process-compose's", svcLog)
+	}
+	// process-compose's own log is rotated, like the logs of the services.
+	if _, err := os.Stat(rotatedLogPath(pcLog, 1)); err != nil {
+		t.Errorf("got stat error %v, want process-compose's log to be rotated", err)
+	}
+}
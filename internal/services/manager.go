@@ -12,107 +12,69 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
-	"go.jetpack.io/devbox/internal/cuecfg"
-	"go.jetpack.io/devbox/internal/xdg"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/ux"
 )
 
 const (
-	processComposeLogfile = ".devbox/compose.log"
-	startingPort          = 8260
-	maxPortTries          = 10
-	fileLockTimeout       = 5 * time.Second
+	// lockPath is the file, relative to the project, that is locked while
+	// process-compose is started, so that concurrent commands don't start it
+	// twice.
+	lockPath        = ".devbox/process-compose.lock"
+	fileLockTimeout = 5 * time.Second
 )
 
-func getAvailablePort(config *globalProcessComposeConfig) (int, bool) {
-	for i := 0; i < maxPortTries; i++ {
-		port := startingPort + i
-		available := true
-		for _, instance := range config.Instances {
-			if instance.Port == port {
-				available = false
-			}
-		}
-		if available {
-			return port, true
-		}
-	}
-	return 0, false
-}
-
-type instance struct {
-	Pid  int `json:"pid"`
-	Port int `json:"port"`
-}
-
-type instanceMap = map[string]instance
-
-type globalProcessComposeConfig struct {
-	Instances instanceMap
-	Path      string   `json:"-"`
-	File      *os.File `json:"-"`
-}
-
-func newGlobalProcessComposeConfig() *globalProcessComposeConfig {
-	return &globalProcessComposeConfig{Instances: map[string]instance{}}
-}
-
-func globalProcessComposeJSONPath() (string, error) {
-	path := xdg.DataSubpath(filepath.Join("devbox", "global"))
-	return filepath.Join(path, "process-compose.json"), errors.WithStack(os.MkdirAll(path, 0755))
-}
-
-func readGlobalProcessComposeJSON(file *os.File) *globalProcessComposeConfig {
-	config := newGlobalProcessComposeConfig()
-
-	err := errors.WithStack(cuecfg.ParseFile(file.Name(), &config.Instances))
-	if err != nil {
-		return config
-	}
-	config.Path = file.Name()
-	return config
+// RunningInstance is a running process-compose instance of a project.
+type RunningInstance struct {
+	ProjectDir string    `json:"project_dir"`
+	Pid        int       `json:"pid"`
+	Port       int       `json:"port"`
+	Started    time.Time `json:"started"`
 }
 
-func writeGlobalProcessComposeJSON(config *globalProcessComposeConfig, file *os.File) error {
-	// convert config to json using cue
-	json, err := cuecfg.MarshalJSON(config.Instances)
-	if err != nil {
-		return fmt.Errorf("failed to convert config to json: %w", err)
-	}
-
-	if err := file.Truncate(0); err != nil {
-		return fmt.Errorf("failed to truncate global config file: %w", err)
-	}
-
-	if _, err := file.Write(json); err != nil {
-		return fmt.Errorf("failed to write global config file: %w", err)
-	}
-
-	return nil
+// RunningInstances returns the running process-compose instances of the
+// projects in projectDirs. Projects whose instance can't be read are skipped
+// with a warning.
+func RunningInstances(w io.Writer, projectDirs []string) []RunningInstance {
+	instances := []RunningInstance{}
+	for _, dir := range projectDirs {
+		inst, err := readInstance(dir)
+		if err != nil {
+			ux.Fwarning(w, "failed to read the process-compose instance of %s: %v\n", dir, err)
+			continue
+		}
+		if inst == nil {
+			continue
+		}
+		instances = append(instances, RunningInstance{
+			ProjectDir: dir,
+			Pid:        inst.Pid,
+			Port:       inst.Port,
+			Started:    inst.Started,
+		})
+	}
+	return instances
 }
 
-func openGlobalConfigFile() (*os.File, error) {
-	configPath, err := globalProcessComposeJSONPath()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get config path: %w", err)
+func openLockFile(projectDir string) (*os.File, error) {
+	path := filepath.Join(projectDir, lockPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.WithStack(err)
 	}
-
-	globalConfigFile, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE, 0664)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0664)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open config file: %w", err)
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
 	}
-
-	err = lockFile(globalConfigFile)
-	if err != nil {
+	if err := fileutil.LockFile(file, fileLockTimeout); err != nil {
 		return nil, fmt.Errorf("failed to lock file: %w", err)
 	}
-
-	return globalConfigFile, nil
+	return file, nil
 }
 
 func StartProcessManager(
@@ -125,29 +87,22 @@ func StartProcessManager(
 	processComposeFilePath string,
 	processComposeBackground bool,
 ) error {
-	// Check if process-compose is already running
-
-	if ProcessManagerIsRunning(projectDir) {
-		return fmt.Errorf("process-compose is already running. To stop it, run `devbox services stop`")
-	}
-
-	// Get the file and lock it right at the start
-
-	configFile, err := openGlobalConfigFile()
+	// Lock the project right at the start, so that the check below stays
+	// true until the new instance is recorded.
+	lock, err := openLockFile(projectDir)
 	if err != nil {
 		return err
 	}
+	defer lock.Close()
 
-	defer configFile.Close()
-
-	// Read the global config file
-	config := readGlobalProcessComposeJSON(configFile)
-	config.File = configFile
+	if ProcessManagerIsRunning(projectDir) {
+		return fmt.Errorf("process-compose is already running. To stop it, run `devbox services stop`")
+	}
 
 	// Get the port to use for this project
-	port, available := getAvailablePort(config)
-	if !available {
-		return fmt.Errorf("no available ports to start process-compose. You should run `devbox services stop` in your projects to free up ports")
+	port, err := availablePort()
+	if err != nil {
+		return fmt.Errorf("no available port to start process-compose: %w", err)
 	}
 
 	// Start building the process-compose command
@@ -166,42 +121,60 @@ func StartProcessManager(
 		fmt.Fprintf(w, "Starting all services: %s \n", strings.Join(services, ", "))
 	}
 
+	inst := &instance{Port: port}
 	for _, s := range availableServices {
-		flags = append(flags, "-f", s.ProcessComposePath)
+		inst.Files = append(inst.Files, s.ProcessComposePath)
+	}
+	logLocations, err := writeLogLocations(projectDir, availableServices)
+	if err != nil {
+		return err
+	}
+	inst.Files = append(inst.Files, logLocations)
+	for _, file := range inst.Files {
+		flags = append(flags, "-f", file)
 	}
 
 	if processComposeBackground {
 		flags = append(flags, "-t=false")
 		cmd := exec.Command(processComposeBinPath, flags...)
-		return runProcessManagerInBackground(cmd, config, port, projectDir)
+		return runProcessManagerInBackground(cmd, inst, projectDir)
 	}
 
 	cmd := exec.Command(processComposeBinPath, flags...)
-	return runProcessManagerInForeground(cmd, config, port, projectDir, w)
+	return runProcessManagerInForeground(cmd, lock, inst, projectDir, w)
 }
 
-func runProcessManagerInForeground(cmd *exec.Cmd, config *globalProcessComposeConfig, port int, projectDir string, w io.Writer) error {
-
+// startInstance starts process-compose and records its instance. inst has the
+// port and files that process-compose is started with.
+func startInstance(cmd *exec.Cmd, inst *instance, projectDir string) error {
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start process-compose: %w", err)
 	}
 
-	projectConfig := instance{
-		Pid:  cmd.Process.Pid,
-		Port: port,
+	inst.Pid = cmd.Process.Pid
+	inst.Started = time.Now().UTC().Truncate(time.Second)
+	var err error
+	if inst.StartTime, err = processStartTime(inst.Pid); err != nil {
+		return err
 	}
+	if err := writeInstance(projectDir, inst); err != nil {
+		return fmt.Errorf("failed to record process-compose instance: %w", err)
+	}
+	return nil
+}
 
-	config.Instances[projectDir] = projectConfig
-
-	err := writeGlobalProcessComposeJSON(config, config.File)
-	if err != nil {
+func runProcessManagerInForeground(cmd *exec.Cmd, lock *os.File, inst *instance, projectDir string, w io.Writer) error {
+	if err := startInstance(cmd, inst, projectDir); err != nil {
 		return err
 	}
 
-	// We're waiting now, so we can unlock the file
-	config.File.Close()
+	// We're waiting now, so we can unlock the project
+	lock.Close()
 
-	err = cmd.Wait()
+	err := cmd.Wait()
+	if removeErr := removeInstance(projectDir, cmd.Process.Pid); removeErr != nil {
+		return removeErr
+	}
 	if err != nil {
 		if err.Error() == "exit status 1" {
 			fmt.Fprintf(w, "Process-compose was terminated remotely, %s\n", err.Error())
@@ -209,21 +182,15 @@ func runProcessManagerInForeground(cmd *exec.Cmd, config *globalProcessComposeCo
 		}
 		return err
 	}
-
-	configFile, err := openGlobalConfigFile()
-	if err != nil {
-		return err
-	}
-
-	config = readGlobalProcessComposeJSON(configFile)
-
-	delete(config.Instances, projectDir)
-	return writeGlobalProcessComposeJSON(config, configFile)
+	return nil
 }
 
-func runProcessManagerInBackground(cmd *exec.Cmd, config *globalProcessComposeConfig, port int, projectDir string) error {
-
-	logfile, err := os.OpenFile(processComposeLogfile, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0664)
+func runProcessManagerInBackground(cmd *exec.Cmd, inst *instance, projectDir string) error {
+	logfile, err := os.OpenFile(
+		filepath.Join(projectDir, processComposeLogPath),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
+		0664,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to open process-compose log file: %w", err)
 	}
@@ -231,144 +198,71 @@ func runProcessManagerInBackground(cmd *exec.Cmd, config *globalProcessComposeCo
 	cmd.Stdout = logfile
 	cmd.Stderr = logfile
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start process-compose: %w", err)
-	}
-
-	projectConfig := instance{
-		Pid:  cmd.Process.Pid,
-		Port: port,
-	}
-
-	config.Instances[projectDir] = projectConfig
-
-	err = writeGlobalProcessComposeJSON(config, config.File)
-	if err != nil {
-		return fmt.Errorf("failed to write global process-compose config: %w", err)
-	}
-
-	return nil
+	return startInstance(cmd, inst, projectDir)
 }
 
 func StopProcessManager(ctx context.Context, projectDir string, w io.Writer) error {
-	configFile, err := openGlobalConfigFile()
+	inst, err := readInstance(projectDir)
 	if err != nil {
 		return err
 	}
-
-	defer configFile.Close()
-
-	config := readGlobalProcessComposeJSON(configFile)
-
-	project, ok := config.Instances[projectDir]
-	if !ok {
-		return fmt.Errorf("process-compose is not running or it's config is missing. To start it, run `devbox services up`")
-	}
-
-	defer func() {
-		delete(config.Instances, projectDir)
-		err = writeGlobalProcessComposeJSON(config, configFile)
-	}()
-
-	pid, _ := os.FindProcess(project.Pid)
-	err = pid.Signal(os.Interrupt)
-	if err != nil {
+	if inst == nil {
 		return fmt.Errorf("process-compose is not running. To start it, run `devbox services up`")
 	}
 
+	if err := stopInstance(projectDir, inst); err != nil {
+		return err
+	}
 	fmt.Fprintf(w, "Process-compose stopped successfully.\n")
 	return nil
 }
 
-func StopAllProcessManagers(ctx context.Context, w io.Writer) error {
-	configFile, err := openGlobalConfigFile()
-	if err != nil {
-		return err
-	}
-
-	defer configFile.Close()
-
-	config := readGlobalProcessComposeJSON(configFile)
-
-	for _, project := range config.Instances {
-		pid, _ := os.FindProcess(project.Pid)
-		err := pid.Signal(os.Interrupt)
+// StopAllProcessManagers stops the process-compose instances of the projects
+// in projectDirs. Failing to stop the instance of a project doesn't prevent
+// stopping the others, so errors are printed as warnings.
+func StopAllProcessManagers(ctx context.Context, w io.Writer, projectDirs []string) error {
+	for _, dir := range projectDirs {
+		inst, err := readInstance(dir)
 		if err != nil {
-			fmt.Printf("process-compose is not running. To start it, run `devbox services up`")
+			ux.Fwarning(w, "failed to read the process-compose instance of %s: %v\n", dir, err)
+			continue
 		}
+		if inst == nil {
+			continue
+		}
+		if err := stopInstance(dir, inst); err != nil {
+			ux.Fwarning(w, "failed to stop process-compose in %s: %v\n", dir, err)
+			continue
+		}
+		fmt.Fprintf(w, "Stopped process-compose in %s\n", dir)
 	}
-
-	config.Instances = make(map[string]instance)
-
-	err = writeGlobalProcessComposeJSON(config, configFile)
-	if err != nil {
-		return fmt.Errorf("failed to write global process-compose config: %w", err)
-	}
-
 	return nil
 }
 
-func ProcessManagerIsRunning(projectDir string) bool {
-
-	configFile, err := openGlobalConfigFile()
-	if err != nil {
-		return false
-	}
-
-	defer configFile.Close()
-
-	config := readGlobalProcessComposeJSON(configFile)
-
-	project, ok := config.Instances[projectDir]
-	if !ok {
-		return false
+func stopInstance(projectDir string, inst *instance) error {
+	process, _ := os.FindProcess(inst.Pid)
+	if err := process.Signal(os.Interrupt); err != nil {
+		return fmt.Errorf("failed to stop process-compose: %w", err)
 	}
+	return removeInstance(projectDir, inst.Pid)
+}
 
-	process, _ := os.FindProcess(project.Pid)
-
-	err = process.Signal(syscall.Signal(0))
+func ProcessManagerIsRunning(projectDir string) bool {
+	inst, err := readInstance(projectDir)
 	if err != nil {
-		delete(config.Instances, projectDir)
-		_ = writeGlobalProcessComposeJSON(config, configFile)
+		debug.Log("failed to read the process-compose instance of %s: %v", projectDir, err)
 		return false
 	}
-	return true
+	return inst != nil
 }
 
 func GetProcessManagerPort(projectDir string) (int, error) {
-	configFile, err := openGlobalConfigFile()
+	inst, err := readInstance(projectDir)
 	if err != nil {
 		return 0, err
 	}
-
-	config := readGlobalProcessComposeJSON(configFile)
-
-	project, ok := config.Instances[projectDir]
-	if !ok {
-		return 0, usererr.WithUserMessage(fmt.Errorf("failed to find projectDir %s in config.Instances", projectDir), "process-compose is not running or it's config is missing. To start it, run `devbox services up`")
-	}
-
-	return project.Port, nil
-}
-
-func lockFile(file *os.File) error {
-	lockResult := make(chan error)
-
-	go func() {
-		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
-		lockResult <- err
-	}()
-
-	select {
-	case err := <-lockResult:
-		if err != nil {
-			file.Close()
-			return fmt.Errorf("failed to lock file: %w", err)
-		}
-		return nil
-
-	case <-time.After(fileLockTimeout):
-		file.Close()
-		return fmt.Errorf("process-compose file lock timed out after %d seconds", fileLockTimeout/time.Second)
+	if inst == nil {
+		return 0, usererr.New("process-compose is not running. To start it, run `devbox services up`")
 	}
+	return inst.Port, nil
 }
@@ -0,0 +1,209 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// tcpListenState is the state of listening sockets in /proc/<pid>/net/tcp.
+const tcpListenState = "0A"
+
+// ListeningPorts returns the TCP ports that the process with pid, or any of
+// its descendants, listens on. Descendants are included because
+// process-compose runs the command of a service in a shell, which usually
+// starts the process that listens. Processes that can't be inspected, for
+// example because they belong to another user, are skipped.
+func ListeningPorts(pid int) ([]int, error) {
+	if _, err := os.Stat("/proc/self/stat"); err == nil {
+		return procListeningPorts(pid)
+	}
+	// Other systems, like macOS, don't have /proc.
+	return lsofListeningPorts(pid)
+}
+
+func procListeningPorts(pid int) ([]int, error) {
+	children, err := procChildren()
+	if err != nil {
+		return nil, err
+	}
+
+	inodes := map[string]bool{}
+	for _, p := range descendants(pid, children) {
+		fdDir := filepath.Join("/proc", strconv.Itoa(p), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			if inode, ok := strings.CutPrefix(link, "socket:["); ok {
+				inodes[strings.TrimSuffix(inode, "]")] = true
+			}
+		}
+	}
+	if len(inodes) == 0 {
+		return []int{}, nil
+	}
+
+	ports := map[int]bool{}
+	for _, name := range []string{"tcp", "tcp6"} {
+		path := filepath.Join("/proc", strconv.Itoa(pid), "net", name)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, port := range parseProcNetTCP(data, inodes) {
+			ports[port] = true
+		}
+	}
+	return sortedPorts(ports), nil
+}
+
+// parseProcNetTCP returns the ports of the listening sockets in a
+// /proc/<pid>/net/tcp file whose inodes are in inodes.
+func parseProcNetTCP(data []byte, inodes map[string]bool) []int {
+	ports := []int{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// Skip the header.
+	scanner.Scan()
+	for scanner.Scan() {
+		// The fields are sl, local_address, rem_address, st, tx_queue:rx_queue,
+		// tr:tm->when, retrnsmt, uid, timeout and inode.
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListenState || !inodes[fields[9]] {
+			continue
+		}
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseInt(hexPort, 16, 32)
+		if err != nil {
+			continue
+		}
+		ports = append(ports, int(port))
+	}
+	return ports
+}
+
+// procChildren maps the PID of each process to the PIDs of its children.
+func procChildren() (map[int][]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	children := map[int][]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			// The process exited.
+			continue
+		}
+		// The second field is the name of the executable in parentheses, which
+		// can contain spaces. The parent's PID is the second field after it.
+		fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		children[ppid] = append(children[ppid], pid)
+	}
+	return children, nil
+}
+
+// descendants returns pid and the PIDs of all of its descendants.
+func descendants(pid int, children map[int][]int) []int {
+	pids := []int{pid}
+	for i := 0; i < len(pids); i++ {
+		pids = append(pids, children[pids[i]]...)
+	}
+	return pids
+}
+
+func lsofListeningPorts(pid int) ([]int, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=").Output()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	children := map[int][]int{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		child, err1 := strconv.Atoi(fields[0])
+		parent, err2 := strconv.Atoi(fields[1])
+		if err1 == nil && err2 == nil {
+			children[parent] = append(children[parent], child)
+		}
+	}
+	pids := []string{}
+	for _, p := range descendants(pid, children) {
+		pids = append(pids, strconv.Itoa(p))
+	}
+
+	out, err = exec.Command(
+		"lsof", "-nP", "-a", "-iTCP", "-sTCP:LISTEN", "-Fn", "-p", strings.Join(pids, ","),
+	).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(out) == 0 {
+		// lsof exits with 1 if no process listens.
+		return []int{}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return parseLsof(out), nil
+}
+
+// parseLsof returns the ports in the output of lsof -Fn, whose names are
+// lines like n*:8080, n127.0.0.1:5432 or n[::1]:5432.
+func parseLsof(out []byte) []int {
+	ports := map[int]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		name, ok := strings.CutPrefix(line, "n")
+		if !ok {
+			continue
+		}
+		port, err := strconv.Atoi(name[strings.LastIndexByte(name, ':')+1:])
+		if err != nil {
+			continue
+		}
+		ports[port] = true
+	}
+	return sortedPorts(ports)
+}
+
+func sortedPorts(ports map[int]bool) []int {
+	sorted := maps.Keys(ports)
+	slices.Sort(sorted)
+	if sorted == nil {
+		return []int{}
+	}
+	return sorted
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/exp/slices"
+)
+
+func TestParseProcNetTCP(t *testing.T) {
+	data := []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1538 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 2000 1 0000000000000000 100 0 0 10 0
+   3: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 1003 1 0000000000000000 100 0 0 10 0
+`)
+	inodes := map[string]bool{"1001": true, "1002": true, "1003": true}
+	// 3306 belongs to another process, and 1003 is an established connection.
+	want := []int{8080, 5432}
+	if diff := cmp.Diff(want, parseProcNetTCP(data, inodes)); diff != "" {
+		t.Errorf("wrong ports (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseLsof(t *testing.T) {
+	out := []byte("p123\nf5\nn*:8080\nf6\nn[::1]:5432\np124\nf3\nn127.0.0.1:5432\n")
+	want := []int{5432, 8080}
+	if diff := cmp.Diff(want, parseLsof(out)); diff != "" {
+		t.Errorf("wrong ports (-want +got):\n%s", diff)
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	children := map[int][]int{1: {2, 3}, 2: {4}, 4: {5}, 6: {7}}
+	want := []int{2, 4, 5}
+	if diff := cmp.Diff(want, descendants(2, children)); diff != "" {
+		t.Errorf("wrong descendants (-want +got):\n%s", diff)
+	}
+}
+
+func TestListeningPorts(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("only runs on systems with /proc")
+	}
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	ports, err := ListeningPorts(os.Getpid())
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !slices.Contains(ports, port) {
+		t.Errorf("got ports %v, want them to contain %d", ports, port)
+	}
+}
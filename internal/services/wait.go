@@ -0,0 +1,214 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/f1bonacc1/process-compose/src/types"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+const (
+	// DefaultWaitTimeout is how long WaitForServices waits by default.
+	DefaultWaitTimeout = 2 * time.Minute
+	waitPollInterval   = 500 * time.Millisecond
+)
+
+// WaitForServices polls process-compose until the named services are ready,
+// or all of its services if names is empty. A service is ready when its
+// readiness probe succeeds, or when it's running if it doesn't have one.
+// Services that complete successfully, like database migrations, are also
+// ready. It returns an error if a service fails, if process-compose stops, or
+// if the services aren't ready after timeout.
+func WaitForServices(
+	ctx context.Context,
+	w io.Writer,
+	projectDir string,
+	names []string,
+	timeout time.Duration,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if len(names) > 0 {
+		fmt.Fprintf(w, "Waiting for services to be ready: %s\n", strings.Join(names, ", "))
+	} else {
+		fmt.Fprintln(w, "Waiting for all services to be ready")
+	}
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+	pending := names
+	for {
+		if !ProcessManagerIsRunning(projectDir) {
+			return usererr.New(
+				"process-compose stopped before the services were ready. See %s for details",
+				processComposeLogPath,
+			)
+		}
+
+		processes, err := ListServices(ctx, projectDir, w)
+		if err != nil {
+			// process-compose doesn't answer until it has started.
+			debug.Log("failed to list services, retrying: %v", err)
+		} else {
+			probed, err := probedServices(projectDir)
+			if err != nil {
+				return err
+			}
+			pending, err = pendingServices(processes, names, probed)
+			if err != nil {
+				return err
+			}
+			if len(pending) == 0 {
+				ux.Fsuccess(w, "Services are ready\n")
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errors.WithStack(ctx.Err())
+			}
+			if len(pending) == 0 {
+				return usererr.New("process-compose didn't respond after %s", timeout)
+			}
+			return usererr.New(
+				"services were not ready after %s: %s. Run `devbox services logs` to see why",
+				timeout, strings.Join(pending, ", "),
+			)
+		case <-ticker.C:
+		}
+	}
+}
+
+// StartStoppedServices starts the named services that process-compose isn't
+// running, for example because they were stopped with `devbox services stop`
+// or because process-compose was started with other services. It returns the
+// names of the services that it started, even if it failed to start the rest.
+func StartStoppedServices(ctx context.Context, w io.Writer, projectDir string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	processes, err := ListServices(ctx, projectDir, w)
+	if err != nil {
+		return nil, err
+	}
+	started := []string{}
+	for _, name := range stoppedServices(processes, names) {
+		if err := StartServices(ctx, w, name, projectDir); err != nil {
+			return started, err
+		}
+		started = append(started, name)
+	}
+	return started, nil
+}
+
+// stoppedServices returns the names of the services that process-compose
+// won't run unless they're started.
+func stoppedServices(processes []Process, names []string) []string {
+	states := map[string]string{}
+	for _, process := range processes {
+		states[process.Name] = process.Status
+	}
+	stopped := []string{}
+	for _, name := range names {
+		switch states[name] {
+		case types.ProcessStateCompleted, types.ProcessStateError, types.ProcessStateDisabled:
+			stopped = append(stopped, name)
+		}
+	}
+	return stopped
+}
+
+// probedServices returns the names of the services that have a readiness probe
+// in the files that the project's process-compose was started with.
+func probedServices(projectDir string) (map[string]bool, error) {
+	inst, err := readInstance(projectDir)
+	if err != nil || inst == nil {
+		return nil, err
+	}
+	probed := map[string]bool{}
+	for _, file := range inst.Files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		project := struct {
+			Processes map[string]struct {
+				ReadinessProbe *yaml.Node `yaml:"readiness_probe"`
+			} `yaml:"processes"`
+		}{}
+		if err := yaml.Unmarshal(data, &project); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", file)
+		}
+		for name, process := range project.Processes {
+			if process.ReadinessProbe != nil {
+				probed[name] = true
+			}
+		}
+	}
+	return probed, nil
+}
+
+// pendingServices returns the names of the services that aren't ready yet, or
+// an error if one of them failed. It checks all processes if names is empty.
+// Services in probed have a readiness probe, so they aren't ready until it
+// succeeds. process-compose reports them as running with an unknown health
+// until the probe first runs.
+func pendingServices(processes []Process, names []string, probed map[string]bool) ([]string, error) {
+	byName := map[string]Process{}
+	for _, process := range processes {
+		byName[process.Name] = process
+	}
+	if len(names) == 0 {
+		for _, process := range processes {
+			names = append(names, process.Name)
+		}
+	}
+
+	pending := []string{}
+	for _, name := range names {
+		process, ok := byName[name]
+		if !ok {
+			// process-compose hasn't loaded its processes yet.
+			pending = append(pending, name)
+			continue
+		}
+		switch process.Status {
+		case types.ProcessStateRunning, types.ProcessStateLaunched:
+			if process.Health == types.ProcessHealthNotReady ||
+				(probed[name] && process.Health != types.ProcessHealthReady) {
+				pending = append(pending, name)
+			}
+		case types.ProcessStateCompleted:
+			if process.ExitCode != 0 {
+				return nil, usererr.New(
+					"service %s exited with code %d. Run `devbox services logs %s` to see why",
+					name, process.ExitCode, name,
+				)
+			}
+		case types.ProcessStateError:
+			return nil, usererr.New(
+				"service %s failed to start. Run `devbox services logs %s` to see why", name, name)
+		case types.ProcessStateDisabled:
+			return nil, usererr.New("service %s is disabled", name)
+		default:
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
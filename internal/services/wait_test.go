@@ -0,0 +1,118 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/f1bonacc1/process-compose/src/types"
+	"github.com/google/go-cmp/cmp"
+
+	"go.jetpack.io/devbox/internal/envir"
+)
+
+func TestPendingServices(t *testing.T) {
+	processes := []Process{
+		{Name: "db", Status: types.ProcessStateRunning, Health: types.ProcessHealthReady},
+		{Name: "cache", Status: types.ProcessStateRunning, Health: types.ProcessHealthUnknown},
+		{Name: "web", Status: types.ProcessStateRunning, Health: types.ProcessHealthNotReady},
+		{Name: "migrate", Status: types.ProcessStateCompleted},
+		{Name: "worker", Status: types.ProcessStatePending},
+		{Name: "api", Status: types.ProcessStateRunning, Health: types.ProcessHealthUnknown},
+	}
+	// The probe of api hasn't run yet.
+	probed := map[string]bool{"db": true, "web": true, "api": true}
+
+	cases := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{"ready", []string{"db", "cache", "migrate"}, []string{}},
+		{"not ready", []string{"db", "web", "worker"}, []string{"web", "worker"}},
+		{"not loaded yet", []string{"search"}, []string{"search"}},
+		{"probe not run yet", []string{"db", "api"}, []string{"api"}},
+		{"all services", nil, []string{"web", "worker", "api"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pendingServices(processes, tc.names, probed)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("wrong pending services (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPendingServicesFailed(t *testing.T) {
+	for _, process := range []Process{
+		{Name: "svc", Status: types.ProcessStateCompleted, ExitCode: 1},
+		{Name: "svc", Status: types.ProcessStateError},
+		{Name: "svc", Status: types.ProcessStateDisabled},
+	} {
+		if _, err := pendingServices([]Process{process}, []string{"svc"}, nil); err == nil {
+			t.Errorf("got nil error for %+v, want error", process)
+		}
+	}
+}
+
+func TestStoppedServices(t *testing.T) {
+	processes := []Process{
+		{Name: "db", Status: types.ProcessStateRunning},
+		{Name: "web", Status: types.ProcessStateCompleted, ExitCode: -1},
+		{Name: "worker", Status: types.ProcessStatePending},
+		{Name: "cache", Status: types.ProcessStateDisabled},
+	}
+	got := stoppedServices(processes, []string{"db", "web", "worker", "cache"})
+	if diff := cmp.Diff([]string{"web", "cache"}, got); diff != "" {
+		t.Errorf("wrong stopped services (-want +got):\n%s", diff)
+	}
+}
+
+func TestProbedServices(t *testing.T) {
+	t.Setenv(envir.XDGDataHome, t.TempDir())
+	projectDir := t.TempDir()
+	probed, err := probedServices(projectDir)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(probed) != 0 {
+		t.Errorf("got probed services %v without a running instance, want none", probed)
+	}
+
+	file := filepath.Join(projectDir, "process-compose.yaml")
+	content := `processes:
+  db:
+    command: postgres
+    readiness_probe:
+      exec:
+        command: pg_isready
+  web:
+    command: npm start
+`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	startTime, err := processStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst := &instance{Pid: os.Getpid(), Port: 8260, StartTime: startTime, Files: []string{file}}
+	if err := writeInstance(projectDir, inst); err != nil {
+		t.Fatal(err)
+	}
+
+	probed, err = probedServices(projectDir)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if diff := cmp.Diff(map[string]bool{"db": true}, probed); diff != "" {
+		t.Errorf("wrong probed services (-want +got):\n%s", diff)
+	}
+}
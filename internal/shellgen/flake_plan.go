@@ -11,7 +11,6 @@ import (
 // flakePlan contains the data to populate the top level flake.nix file
 // that builds the devbox environment
 type flakePlan struct {
-	BinaryCache string
 	NixpkgsInfo *NixpkgsInfo
 	FlakeInputs []*flakeInput
 	Packages    []*devpkg.Package
@@ -56,7 +55,7 @@ func newFlakePlan(ctx context.Context, devbox devboxer) (*flakePlan, error) {
 		return nil, err
 	}
 
-	nixpkgsInfo := getNixpkgsInfo(devbox.Config().NixPkgsCommitHash())
+	nixpkgsInfo := getNixpkgsInfo(devbox.EffectiveConfig().NixPkgsCommitHash())
 
 	// This is an optimization. Try to reuse the nixpkgs info from the flake
 	// inputs to avoid introducing a new one.
@@ -73,7 +72,6 @@ func newFlakePlan(ctx context.Context, devbox devboxer) (*flakePlan, error) {
 	}
 
 	return &flakePlan{
-		BinaryCache: devpkg.BinaryCache,
 		FlakeInputs: flakeInputs,
 		NixpkgsInfo: nixpkgsInfo,
 		Packages:    packages,
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/alessio/shellescape"
 	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/boxcli/featureflag"
 	"go.jetpack.io/devbox/internal/debug"
@@ -22,6 +23,7 @@ const HooksFilename = ".hooks"
 
 type devboxer interface {
 	Config() *devconfig.Config
+	EffectiveConfig() *devconfig.Config
 	Lockfile() *lock.File
 	PackagesAsInputs() []*devpkg.Package
 	PluginManager() *plugin.Manager
@@ -48,7 +50,7 @@ func WriteScriptsToFiles(devbox devboxer) error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	hooks := strings.Join(append(pluginHooks, devbox.Config().InitHook().String()), "\n\n")
+	hooks := strings.Join(append(pluginHooks, devbox.EffectiveConfig().InitHook().String()), "\n\n")
 	// always write it, even if there are no hooks, because scripts will source it.
 	err = WriteScriptFile(devbox, HooksFilename, hooks)
 	if err != nil {
@@ -57,8 +59,15 @@ func WriteScriptsToFiles(devbox devboxer) error {
 	written[HooksFilename] = struct{}{}
 
 	// Write scripts to files.
-	for name, body := range devbox.Config().Scripts() {
-		err = WriteScriptFile(devbox, name, ScriptBody(devbox, body.String()))
+	for name, script := range devbox.EffectiveConfig().Scripts() {
+		body := script.String()
+		if script.Cwd != "" {
+			// Change directory after sourcing the hooks, so that hooks always run
+			// in the project's directory.
+			dir := filepath.Join(devbox.ProjectDir(), script.Cwd)
+			body = fmt.Sprintf("cd %s || exit 1\n\n%s", shellescape.Quote(dir), body)
+		}
+		err = WriteScriptFile(devbox, name, ScriptBody(devbox, body))
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -0,0 +1,65 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package watch
+
+import (
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+type ignorePattern struct {
+	glob    string
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher matches paths against .gitignore patterns. It supports the
+// common subset of the .gitignore syntax: negation with "!", patterns that
+// only match directories with a trailing "/", patterns that are anchored to
+// the root when they contain a "/", and "**".
+type ignoreMatcher []ignorePattern
+
+func newIgnoreMatcher(patterns []string) ignoreMatcher {
+	m := ignoreMatcher{}
+	for _, pattern := range patterns {
+		p := ignorePattern{}
+		if strings.HasPrefix(pattern, "!") {
+			p.negate = true
+			pattern = pattern[1:]
+		} else if strings.HasPrefix(pattern, `\`) {
+			// Escapes a leading "!" or "#".
+			pattern = pattern[1:]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			p.dirOnly = true
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		if strings.Contains(pattern, "/") {
+			p.glob = strings.TrimPrefix(pattern, "/")
+		} else {
+			p.glob = "**/" + pattern
+		}
+		if pattern == "" || !doublestar.ValidatePattern(p.glob) {
+			continue
+		}
+		m = append(m, p)
+	}
+	return m
+}
+
+// match returns true if the path, relative to the root and separated by "/", is
+// ignored. Like git, the last pattern that matches the path wins.
+func (m ignoreMatcher) match(path string, isDir bool) bool {
+	ignored := false
+	for _, p := range m {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if ok, _ := doublestar.Match(p.glob, path); ok {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
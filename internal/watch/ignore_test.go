@@ -0,0 +1,41 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package watch
+
+import "testing"
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := newIgnoreMatcher([]string{
+		"*.log",
+		"!keep.log",
+		"node_modules/",
+		"/dist",
+		"docs/build",
+		`\!important`,
+	})
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false},
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false},
+		{"dist", true, true},
+		{"web/dist", true, false},
+		{"docs/build", true, true},
+		{"web/docs/build", true, false},
+		{"!important", false, true},
+		{"main.go", false, false},
+	}
+	for _, tc := range cases {
+		if got := m.match(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("match(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
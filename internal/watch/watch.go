@@ -0,0 +1,190 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package watch reports changes to the files of a directory tree that match a
+// set of globs.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/fileutil"
+)
+
+// DefaultDebounce is how long a Watcher waits after the last change before it
+// reports a batch of changes, so that saving many files at once (e.g. when
+// switching git branches) only results in one batch.
+const DefaultDebounce = 300 * time.Millisecond
+
+// alwaysIgnored are ignored in addition to the patterns in .gitignore.
+var alwaysIgnored = []string{".git/", ".devbox/"}
+
+type Opts struct {
+	// Dir is the root of the watched tree. Globs are relative to it.
+	Dir string
+	// Globs select the files to watch, e.g. "**/*.go". They follow the
+	// syntax of github.com/bmatcuk/doublestar.
+	Globs []string
+	// Debounce defaults to DefaultDebounce.
+	Debounce time.Duration
+}
+
+// Watcher watches the files of a directory tree that match a set of globs.
+// Files and directories ignored by the .gitignore file of the tree's root are
+// never watched.
+type Watcher struct {
+	dir      string
+	globs    []string
+	debounce time.Duration
+	ignored  ignoreMatcher
+	fsw      *fsnotify.Watcher
+}
+
+// New starts watching the tree. Call Close to stop watching it.
+func New(opts *Opts) (*Watcher, error) {
+	for _, glob := range opts.Globs {
+		if !doublestar.ValidatePattern(glob) {
+			return nil, errors.Errorf("invalid glob: %s", glob)
+		}
+	}
+	patterns, err := fileutil.GitIgnorePatterns(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	w := &Watcher{
+		dir:      opts.Dir,
+		globs:    opts.Globs,
+		debounce: opts.Debounce,
+		ignored:  newIgnoreMatcher(append(slices.Clone(alwaysIgnored), patterns...)),
+		fsw:      fsw,
+	}
+	if w.debounce == 0 {
+		w.debounce = DefaultDebounce
+	}
+	if err := w.addTree(opts.Dir); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close stops watching the tree.
+func (w *Watcher) Close() error {
+	return errors.WithStack(w.fsw.Close())
+}
+
+// Run calls onChange with the paths, relative to the root of the tree, of the
+// watched files that changed. Changes are reported in batches, once no file
+// has changed for the debounce duration. Changes that happen while onChange
+// runs are reported in the next batch. Run returns when ctx is done.
+func (w *Watcher) Run(ctx context.Context, onChange func(paths []string)) error {
+	changed := map[string]bool{}
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			if path, ok := w.handleEvent(event); ok {
+				changed[path] = true
+				timer.Reset(w.debounce)
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			return errors.WithStack(err)
+		case <-timer.C:
+			paths := maps.Keys(changed)
+			slices.Sort(paths)
+			changed = map[string]bool{}
+			onChange(paths)
+		}
+	}
+}
+
+// handleEvent starts watching new directories, and returns the relative path of
+// the event's file if it is watched.
+func (w *Watcher) handleEvent(event fsnotify.Event) (string, bool) {
+	// Changes of permissions and timestamps don't change the contents of files.
+	if event.Op == fsnotify.Chmod {
+		return "", false
+	}
+	rel, err := filepath.Rel(w.dir, event.Name)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+
+	if event.Has(fsnotify.Create) && fileutil.IsDir(event.Name) {
+		if !w.ignored.match(rel, true) {
+			if err := w.addTree(event.Name); err != nil {
+				debug.Log("failed to watch %s: %v", event.Name, err)
+			}
+		}
+		return "", false
+	}
+	if w.ignored.match(rel, false) || !w.matchesGlobs(rel) {
+		return "", false
+	}
+	return rel, true
+}
+
+func (w *Watcher) matchesGlobs(rel string) bool {
+	for _, glob := range w.globs {
+		// The globs were validated in New, so Match can't fail.
+		if ok, _ := doublestar.Match(glob, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addTree watches dir and all its subdirectories, except ignored ones.
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may have been removed since it was created.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(w.dir, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if rel != "." && w.ignored.match(filepath.ToSlash(rel), true) {
+			return filepath.SkipDir
+		}
+		if err := w.fsw.Add(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.WithStack(err)
+		}
+		return nil
+	})
+}
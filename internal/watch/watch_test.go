@@ -0,0 +1,83 @@
+// Copyright 2023 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".gitignore"), "gen/\n")
+	for _, sub := range []string{"pkg", "gen", ".devbox"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := New(&Opts{Dir: dir, Globs: []string{"**/*.go"}, Debounce: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := make(chan []string)
+	go func() {
+		_ = w.Run(ctx, func(paths []string) { batches <- paths })
+	}()
+
+	writeFile(t, filepath.Join(dir, "main.go"), "package main")
+	writeFile(t, filepath.Join(dir, "pkg", "pkg.go"), "package pkg")
+	writeFile(t, filepath.Join(dir, "README.md"), "ignored: doesn't match")
+	writeFile(t, filepath.Join(dir, "gen", "gen.go"), "ignored: .gitignore")
+	writeFile(t, filepath.Join(dir, ".devbox", "x.go"), "ignored: always")
+
+	select {
+	case got := <-batches:
+		want := []string{"main.go", "pkg/pkg.go"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("wrong changed paths (-want +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for changes")
+	}
+
+	// New directories are watched too.
+	if err := os.Mkdir(filepath.Join(dir, "cmd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Give the watcher time to add the new directory.
+	time.Sleep(100 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "cmd", "cmd.go"), "package cmd")
+
+	select {
+	case got := <-batches:
+		if diff := cmp.Diff([]string{"cmd/cmd.go"}, got); diff != "" {
+			t.Errorf("wrong changed paths (-want +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for changes in new directory")
+	}
+}
+
+func TestNewInvalidGlob(t *testing.T) {
+	if _, err := New(&Opts{Dir: t.TempDir(), Globs: []string{"[a-"}}); err == nil {
+		t.Error("got nil error for invalid glob, want error")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}